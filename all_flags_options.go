@@ -0,0 +1,109 @@
+package ffclient
+
+import (
+	"github.com/thomaspoignant/go-feature-flag/ffuser"
+	"github.com/thomaspoignant/go-feature-flag/internal/flag"
+	"github.com/thomaspoignant/go-feature-flag/internal/flagstate"
+)
+
+// AllFlagsStateOptions selects the flags and the information returned by AllFlagsStateWithOptions.
+type AllFlagsStateOptions struct {
+	// ClientSideOnly keeps only the flags with the field clientSideAvailable set to true,
+	// use it if you send the flags to a client-side application (ex: a browser).
+	// Default: false
+	ClientSideOnly bool
+
+	// WithReasons adds the reason of the evaluation and the version of the flag to each flag.
+	// Default: false
+	WithReasons bool
+
+	// OmitUnexposedValues removes the value of the flags the user is not exposed to, the user is
+	// exposed to a flag if a target, a targeting rule or a percentage of the flag applies to them.
+	// Default: false
+	OmitUnexposedValues bool
+}
+
+// AllFlagsStateWithOptions return the values of the flags for a specific user,
+// the options allow to select the flags and the information returned.
+func AllFlagsStateWithOptions(user ffuser.User, options AllFlagsStateOptions) flagstate.AllFlags {
+	return ff.AllFlagsStateWithOptions(user, options)
+}
+
+// AllFlagsStateWithOptions return a flagstate.AllFlags that contains the flags for a specific user,
+// the options allow to select the flags and the information returned.
+// Note: Use this function only if you are using multiple go-feature-flag instances.
+func (g *GoFeatureFlag) AllFlagsStateWithOptions(user ffuser.User, options AllFlagsStateOptions) flagstate.AllFlags {
+	flags := map[string]flag.Flag{}
+
+	if !g.config.Offline {
+		var err error
+		flags, err = g.cache.AllFlags()
+		if err != nil {
+			// empty AllFlags will set valid to false
+			return flagstate.AllFlags{}
+		}
+	}
+
+	allFlags := flagstate.NewAllFlags()
+	for key, currentFlag := range flags {
+		if options.ClientSideOnly && !currentFlag.GetClientSideAvailable() {
+			continue
+		}
+
+		flagValue, resolutionDetails, err := g.evaluate(key, currentFlag, user)
+		var state flagstate.FlagState
+		var errorCode flag.ErrorCode
+		switch {
+		case err != nil:
+			state = flagstate.NewFlagState(currentFlag.GetTrackEvents(), nil, flag.VariationSDKDefault, true)
+			errorCode = flag.ErrorCodeGeneral
+
+		case resolutionDetails.Variant == "":
+			// the flag has no variation to serve (ex: disabled with a percentage split as default rule),
+			// the client uses its own default value.
+			state = flagstate.NewFlagState(currentFlag.GetTrackEvents(), nil, flag.VariationSDKDefault, false)
+
+		case isSupportedValue(flagValue):
+			state = flagstate.NewFlagState(currentFlag.GetTrackEvents(), flagValue, resolutionDetails.Variant, false)
+
+		default:
+			defaultVariationName := currentFlag.GetDefaultVariation()
+			defaultVariationValue := currentFlag.GetVariationValue(defaultVariationName)
+			state = flagstate.NewFlagState(currentFlag.GetTrackEvents(), defaultVariationValue, defaultVariationName, true)
+			resolutionDetails.Reason = flag.ReasonError
+			errorCode = flag.ErrorCodeTypeMismatch
+		}
+
+		if options.WithReasons {
+			state.Reason = resolutionDetails.Reason
+			state.ErrorCode = errorCode
+			state.Version = currentFlag.GetVersion()
+		}
+		if options.OmitUnexposedValues && !state.Failed && !isExposed(resolutionDetails.Reason) {
+			state.Value = nil
+		}
+		allFlags.AddFlag(key, state)
+	}
+	return allFlags
+}
+
+// isSupportedValue is checking that the value of the flag can be sent in the AllFlags.
+func isSupportedValue(value interface{}) bool {
+	switch value.(type) {
+	case int, float64, bool, string, []interface{}, map[string]interface{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// isExposed returns true if the reason means that a target, a targeting rule or a percentage
+// of the flag applies to the user.
+func isExposed(reason flag.ResolutionReason) bool {
+	switch reason {
+	case flag.ReasonTargetMatch, flag.ReasonRuleMatch, flag.ReasonPercentage:
+		return true
+	default:
+		return false
+	}
+}
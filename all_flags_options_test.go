@@ -0,0 +1,101 @@
+package ffclient_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	ffclient "github.com/thomaspoignant/go-feature-flag"
+	"github.com/thomaspoignant/go-feature-flag/ffuser"
+	"github.com/thomaspoignant/go-feature-flag/internal/flagstate"
+)
+
+func TestAllFlagsStateWithOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options ffclient.AllFlagsStateOptions
+		want    map[string]flagstate.FlagState
+	}{
+		{
+			name:    "no option",
+			options: ffclient.AllFlagsStateOptions{},
+			want: map[string]flagstate.FlagState{
+				"server-flag":    {Value: true, VariationType: "enabled", TrackEvents: true},
+				"checkout-color": {Value: "blue", VariationType: "blue", TrackEvents: true},
+				"new-checkout":   {Value: true, VariationType: "enabled", TrackEvents: true},
+			},
+		},
+		{
+			name:    "client side only",
+			options: ffclient.AllFlagsStateOptions{ClientSideOnly: true},
+			want: map[string]flagstate.FlagState{
+				"checkout-color": {Value: "blue", VariationType: "blue", TrackEvents: true},
+				"new-checkout":   {Value: true, VariationType: "enabled", TrackEvents: true},
+			},
+		},
+		{
+			name:    "with reasons",
+			options: ffclient.AllFlagsStateOptions{ClientSideOnly: true, WithReasons: true},
+			want: map[string]flagstate.FlagState{
+				"checkout-color": {
+					Value: "blue", VariationType: "blue", TrackEvents: true, Reason: ffclient.ReasonDefault, Version: 2,
+				},
+				"new-checkout": {
+					Value: true, VariationType: "enabled", TrackEvents: true, Reason: ffclient.ReasonPercentage,
+				},
+			},
+		},
+		{
+			name:    "omit unexposed values",
+			options: ffclient.AllFlagsStateOptions{ClientSideOnly: true, OmitUnexposedValues: true},
+			want: map[string]flagstate.FlagState{
+				"checkout-color": {VariationType: "blue", TrackEvents: true},
+				"new-checkout":   {Value: true, VariationType: "enabled", TrackEvents: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gffClient, err := ffclient.New(ffclient.Config{
+				PollingInterval: 5 * time.Second,
+				Retriever:       &ffclient.FileRetriever{Path: "testdata/ffclient/all_flags_options/flag-config.yaml"},
+			})
+			assert.NoError(t, err)
+			defer gffClient.Close()
+
+			allFlags := gffClient.AllFlagsStateWithOptions(ffuser.NewUser("random-key"), tt.options)
+			assert.True(t, allFlags.IsValid())
+			got := allFlags.GetFlags()
+			for key, state := range got {
+				assert.NotZero(t, state.Timestamp)
+				state.Timestamp = 0
+				got[key] = state
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestAllFlagsStateWithOptionsJSON(t *testing.T) {
+	gffClient, err := ffclient.New(ffclient.Config{
+		PollingInterval: 5 * time.Second,
+		Retriever:       &ffclient.FileRetriever{Path: "testdata/ffclient/all_flags_options/flag-config.yaml"},
+	})
+	assert.NoError(t, err)
+	defer gffClient.Close()
+
+	allFlags := gffClient.AllFlagsStateWithOptions(ffuser.NewUser("random-key"), ffclient.AllFlagsStateOptions{
+		ClientSideOnly:      true,
+		WithReasons:         true,
+		OmitUnexposedValues: true,
+	})
+	state := allFlags.GetFlags()["checkout-color"]
+	state.Timestamp = 1622209328
+	allFlags.AddFlag("checkout-color", state)
+	delete(allFlags.GetFlags(), "new-checkout")
+
+	got, err := allFlags.MarshalJSON()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"flags":{"checkout-color":{"timestamp":1622209328,"variationType":"blue","trackEvents":true,
+"reason":"DEFAULT","version":2}},"valid":true}`, string(got))
+}
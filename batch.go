@@ -0,0 +1,79 @@
+package ffclient
+
+import (
+	"github.com/thomaspoignant/go-feature-flag/ffuser"
+	"github.com/thomaspoignant/go-feature-flag/internal/flagstate"
+)
+
+// UserIterator provides the users of a batch evaluation one by one,
+// Next returns false once all the users have been provided.
+type UserIterator interface {
+	Next() (ffuser.User, bool)
+}
+
+// NewUserSliceIterator creates a UserIterator providing the users of the slice.
+func NewUserSliceIterator(users []ffuser.User) UserIterator {
+	return &userSliceIterator{users: users}
+}
+
+type userSliceIterator struct {
+	users []ffuser.User
+	index int
+}
+
+func (u *userSliceIterator) Next() (ffuser.User, bool) {
+	if u.index >= len(u.users) {
+		return ffuser.User{}, false
+	}
+	user := u.users[u.index]
+	u.index++
+	return user, true
+}
+
+// BatchOptions configures a batch evaluation.
+type BatchOptions struct {
+	// SkipEvents disables the export of the evaluations of the batch.
+	// Default: false
+	SkipEvents bool
+}
+
+// BatchEvaluate evaluates the flags for every user provided by the iterator,
+// the handler is called for each user with the state of the flags.
+// The flags are read once from the cache at the beginning of the batch, an update of the flags during
+// the batch is not taken into account. The hooks are not called for the evaluations of a batch.
+func BatchEvaluate(
+	flagKeys []string, users UserIterator, options BatchOptions, handler func(user ffuser.User, flags flagstate.AllFlags),
+) error {
+	return ff.BatchEvaluate(flagKeys, users, options, handler)
+}
+
+// BatchEvaluate evaluates the flags for every user provided by the iterator,
+// the handler is called for each user with the state of the flags.
+// The flags are read once from the cache at the beginning of the batch, an update of the flags during
+// the batch is not taken into account. The hooks are not called for the evaluations of a batch.
+// Note: Use this function only if you are using multiple go-feature-flag instances.
+func (g *GoFeatureFlag) BatchEvaluate(
+	flagKeys []string, users UserIterator, options BatchOptions, handler func(user ffuser.User, flags flagstate.AllFlags),
+) error {
+	if g.config.Offline {
+		return ErrOffline
+	}
+	snapshot, err := g.cache.Snapshot()
+	if err != nil {
+		return ErrCacheNotInitialized
+	}
+
+	for user, ok := users.Next(); ok; user, ok = users.Next() {
+		allFlags := flagstate.NewAllFlags()
+		for _, flagKey := range flagKeys {
+			value, varResult, _ := g.rawVariationFrom(snapshot, flagKey, user, nil)
+			if !options.SkipEvents {
+				g.notifyVariation(flagKey, user, varResult, value)
+			}
+			allFlags.AddFlag(
+				flagKey, flagstate.NewFlagState(varResult.TrackEvents, value, varResult.VariationType, varResult.Failed))
+		}
+		handler(user, allFlags)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package ffclient_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	ffclient "github.com/thomaspoignant/go-feature-flag"
+	"github.com/thomaspoignant/go-feature-flag/ffuser"
+	"github.com/thomaspoignant/go-feature-flag/internal/flagstate"
+	"github.com/thomaspoignant/go-feature-flag/testutils/mock"
+)
+
+func TestBatchEvaluate(t *testing.T) {
+	tests := []struct {
+		name       string
+		skipEvents bool
+		wantEvents int
+	}{
+		{
+			name:       "evaluations exported",
+			wantEvents: 9,
+		},
+		{
+			name:       "evaluations not exported",
+			skipEvents: true,
+			wantEvents: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := &mock.Exporter{Bulk: true}
+			gffClient, err := ffclient.New(ffclient.Config{
+				PollingInterval: 5 * time.Second,
+				Retriever:       &ffclient.FileRetriever{Path: "testdata/ffclient/variation_details/flag-config.yaml"},
+				DataExporter: ffclient.DataExporter{
+					FlushInterval:    10 * time.Second,
+					MaxEventInMemory: 1000,
+					Exporter:         exporter,
+				},
+			})
+			assert.NoError(t, err)
+
+			users := []ffuser.User{
+				ffuser.NewUser("targeted-key"),
+				ffuser.NewUserBuilder("beta-user").AddCustom("beta", true).Build(),
+				ffuser.NewUser("random-key"),
+			}
+			got := map[string]map[string]flagstate.FlagState{}
+			err = gffClient.BatchEvaluate(
+				[]string{"checkout-color", "new-checkout", "unknown-flag"},
+				ffclient.NewUserSliceIterator(users),
+				ffclient.BatchOptions{SkipEvents: tt.skipEvents},
+				func(user ffuser.User, flags flagstate.AllFlags) {
+					assert.False(t, flags.IsValid(), "unknown-flag cannot be evaluated")
+					got[user.GetKey()] = flags.GetFlags()
+				})
+			assert.NoError(t, err)
+			gffClient.Close()
+
+			assert.Len(t, got, 3)
+			assert.Equal(t, "green", got["targeted-key"]["checkout-color"].Value)
+			assert.Equal(t, "red", got["beta-user"]["checkout-color"].Value)
+			assert.Equal(t, "blue", got["random-key"]["checkout-color"].Value)
+			assert.Equal(t, true, got["random-key"]["new-checkout"].Value)
+			assert.True(t, got["random-key"]["unknown-flag"].Failed)
+			assert.Equal(t, "SdkDefault", got["random-key"]["unknown-flag"].VariationType)
+			assert.Len(t, exporter.GetExportedEvents(), tt.wantEvents)
+		})
+	}
+}
+
+func TestBatchEvaluateOffline(t *testing.T) {
+	gffClient, err := ffclient.New(ffclient.Config{Offline: true})
+	assert.NoError(t, err)
+	defer gffClient.Close()
+
+	called := false
+	err = gffClient.BatchEvaluate([]string{"new-checkout"},
+		ffclient.NewUserSliceIterator([]ffuser.User{ffuser.NewUser("random-key")}),
+		ffclient.BatchOptions{},
+		func(_ ffuser.User, _ flagstate.AllFlags) { called = true })
+	assert.ErrorIs(t, err, ffclient.ErrOffline)
+	assert.False(t, called)
+}
@@ -51,10 +51,36 @@ type Config struct {
 	// Default: false
 	StartWithRetrieverError bool
 
+	// PersistentFlagConfigurationFile (optional) is the path of a local file where the last flag configuration
+	// retrieved successfully is stored. If the retriever is unreachable when the SDK starts, the flags are loaded
+	// from this file, so a restart during an outage of the retriever still serves the flags.
+	// Default: "" (no persistence)
+	PersistentFlagConfigurationFile string
+
+	// UpdateGuard (optional) refuses the updates of the flag file that look destructive.
+	// The flags in the cache are kept and the rejection is sent to the notifiers.
+	// Default: only the flag files that cannot be loaded are refused
+	UpdateGuard UpdateGuard
+
 	// Offline (optional) If true, the SDK will not try to retrieve the flag file and will not export any data.
 	// No notification will be send neither.
 	// Default: false
 	Offline bool
+
+	// Hooks (optional) is the list of hooks called around the evaluation of the flags.
+	Hooks []Hook
+}
+
+// UpdateGuard is the configuration of the checks made before replacing the flags in the cache.
+// A flag file that cannot be loaded is always refused, a rule that cannot be parsed only never matches.
+type UpdateGuard struct {
+	// MaxDeletedFlagsPercentage (optional) refuses an update deleting more than this percentage of the flags.
+	// Default: 0 (no limit)
+	MaxDeletedFlagsPercentage float64
+
+	// RefuseEmptyFlagFile (optional) If true, a flag file without any flag is refused.
+	// Default: false
+	RefuseEmptyFlagFile bool
 }
 
 // GetRetriever returns a retriever.FlagRetriever configure with the retriever available in the config.
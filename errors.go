@@ -0,0 +1,150 @@
+package ffclient
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/thomaspoignant/go-feature-flag/internal/cache"
+	"github.com/thomaspoignant/go-feature-flag/internal/flag"
+)
+
+const (
+	errorFlagNotAvailable = "flag %v is not present or disabled"
+	errorWrongVariation   = "wrong variation used for flag %v"
+	errorOffline          = "go-feature-flag is offline, the default value is used for flag %v"
+	errorHook             = "hook failed for flag %v: %v"
+	errorNoVariation      = "flag %v has no variation to serve, the default value is used"
+	errorPrerequisiteLoop = "impossible to evaluate flag %v, its chain of prerequisites is too long or circular"
+)
+
+// The errors returned by the variation functions when the default value is served,
+// they can be checked with errors.Is.
+var (
+	// ErrFlagNotFound is used when the flag does not exist.
+	ErrFlagNotFound = errors.New("flag not found")
+
+	// ErrFlagDisabled is used when the flag is disabled.
+	ErrFlagDisabled = errors.New("flag disabled")
+
+	// ErrTypeMismatch is used when the value of the flag is not of the type expected by the variation function.
+	ErrTypeMismatch = errors.New("type mismatch")
+
+	// ErrCacheNotInitialized is used when the flags are not loaded yet.
+	ErrCacheNotInitialized = errors.New("cache not initialized")
+
+	// ErrOffline is used when the client is in offline mode.
+	ErrOffline = errors.New("client offline")
+)
+
+// EvaluationError is the error returned by the variation functions when the default value is served.
+// It wraps one of the sentinel errors (ErrFlagNotFound, ErrFlagDisabled ...) and can be retrieved
+// with errors.As to know the reason of the evaluation.
+type EvaluationError struct {
+	// FlagKey is the key of the flag evaluated.
+	FlagKey string
+
+	// Reason is explaining why the default value has been served.
+	Reason EvaluationReason
+
+	// ErrorCode identifies the error, it is empty if the flag is disabled.
+	ErrorCode ErrorCode
+
+	// Err is the error at the origin of the failure.
+	Err error
+
+	message string
+}
+
+func (e *EvaluationError) Error() string {
+	return e.message
+}
+
+func (e *EvaluationError) Unwrap() error {
+	return e.Err
+}
+
+// newFlagNotAvailableError converts the error returned by the cache to an EvaluationError.
+func newFlagNotAvailableError(flagKey string, err error) error {
+	evalErr := &EvaluationError{
+		FlagKey:   flagKey,
+		Reason:    flag.ReasonError,
+		ErrorCode: flag.ErrorCodeGeneral,
+		Err:       err,
+		message:   fmt.Sprintf(errorFlagNotAvailable, flagKey),
+	}
+	switch {
+	case errors.Is(err, cache.ErrFlagNotFound):
+		evalErr.Reason, evalErr.ErrorCode, evalErr.Err = flag.ReasonFlagNotFound, flag.ErrorCodeFlagNotFound, ErrFlagNotFound
+	case errors.Is(err, cache.ErrNotInitialized):
+		evalErr.Err = ErrCacheNotInitialized
+	}
+	return evalErr
+}
+
+// newFlagDisabledError is the error returned when the flag is disabled,
+// it is not an error of the evaluation so there is no error code.
+func newFlagDisabledError(flagKey string) error {
+	return &EvaluationError{
+		FlagKey: flagKey,
+		Reason:  flag.ReasonDisabled,
+		Err:     ErrFlagDisabled,
+		message: fmt.Sprintf(errorFlagNotAvailable, flagKey),
+	}
+}
+
+// newNoVariationError is the error returned when the evaluation does not select any variation
+// (ex: a disabled flag with a percentage split as default rule), the reason of the evaluation is kept.
+// A flag out of its experimentation window behaves as a disabled flag.
+func newNoVariationError(flagKey string, reason EvaluationReason) error {
+	evalErr := &EvaluationError{
+		FlagKey: flagKey,
+		Reason:  reason,
+		message: fmt.Sprintf(errorNoVariation, flagKey),
+	}
+	if reason == flag.ReasonDisabled || reason == flag.ReasonExperimentNotRunning {
+		evalErr.Err = ErrFlagDisabled
+	}
+	return evalErr
+}
+
+// newPrerequisiteDepthError is the error returned when the prerequisites of the flag cannot be evaluated.
+func newPrerequisiteDepthError(flagKey string) error {
+	return &EvaluationError{
+		FlagKey:   flagKey,
+		Reason:    flag.ReasonError,
+		ErrorCode: flag.ErrorCodeGeneral,
+		message:   fmt.Sprintf(errorPrerequisiteLoop, flagKey),
+	}
+}
+
+// newTypeMismatchError is the error returned when the value of the flag is not of the expected type.
+func newTypeMismatchError(flagKey string) error {
+	return &EvaluationError{
+		FlagKey:   flagKey,
+		Reason:    flag.ReasonTypeMismatch,
+		ErrorCode: flag.ErrorCodeTypeMismatch,
+		Err:       ErrTypeMismatch,
+		message:   fmt.Sprintf(errorWrongVariation, flagKey),
+	}
+}
+
+// newOfflineError is the error returned when the client is offline.
+func newOfflineError(flagKey string) error {
+	return &EvaluationError{
+		FlagKey: flagKey,
+		Reason:  flag.ReasonOffline,
+		Err:     ErrOffline,
+		message: fmt.Sprintf(errorOffline, flagKey),
+	}
+}
+
+// newHookError is the error returned when a hook has failed, the error of the hook is wrapped.
+func newHookError(flagKey string, err error) error {
+	return &EvaluationError{
+		FlagKey:   flagKey,
+		Reason:    flag.ReasonError,
+		ErrorCode: flag.ErrorCodeGeneral,
+		Err:       err,
+		message:   fmt.Sprintf(errorHook, flagKey, err),
+	}
+}
@@ -0,0 +1,97 @@
+package ffclient
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thomaspoignant/go-feature-flag/ffuser"
+	"github.com/thomaspoignant/go-feature-flag/internal/cache"
+	"github.com/thomaspoignant/go-feature-flag/internal/flag"
+	"github.com/thomaspoignant/go-feature-flag/internal/flagv1"
+	"github.com/thomaspoignant/go-feature-flag/testutils/testconvert"
+)
+
+func TestVariationErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		cacheMock     cache.Manager
+		offline       bool
+		wantErr       error
+		wantReason    flag.ResolutionReason
+		wantErrorCode flag.ErrorCode
+		wantMessage   string
+	}{
+		{
+			name: "flag not found",
+			cacheMock: NewCacheMock(&flagv1.FlagData{},
+				fmt.Errorf("flag [test-flag] does not exists: %w", cache.ErrFlagNotFound)),
+			wantErr:       ErrFlagNotFound,
+			wantReason:    flag.ReasonFlagNotFound,
+			wantErrorCode: flag.ErrorCodeFlagNotFound,
+			wantMessage:   "flag test-flag is not present or disabled",
+		},
+		{
+			name:        "flag disabled",
+			cacheMock:   NewCacheMock(&flagv1.FlagData{Disable: testconvert.Bool(true)}, nil),
+			wantErr:     ErrFlagDisabled,
+			wantReason:  flag.ReasonDisabled,
+			wantMessage: "flag test-flag is not present or disabled",
+		},
+		{
+			name: "cache not initialized",
+			cacheMock: NewCacheMock(nil,
+				fmt.Errorf("impossible to read the flag before the initialisation: %w", cache.ErrNotInitialized)),
+			wantErr:       ErrCacheNotInitialized,
+			wantReason:    flag.ReasonError,
+			wantErrorCode: flag.ErrorCodeGeneral,
+			wantMessage:   "flag test-flag is not present or disabled",
+		},
+		{
+			name: "type mismatch",
+			cacheMock: NewCacheMock(&flagv1.FlagData{
+				Percentage: testconvert.Float64(100),
+				True:       testconvert.Interface("true"),
+				False:      testconvert.Interface("false"),
+				Default:    testconvert.Interface("false"),
+			}, nil),
+			wantErr:       ErrTypeMismatch,
+			wantReason:    flag.ReasonTypeMismatch,
+			wantErrorCode: flag.ErrorCodeTypeMismatch,
+			wantMessage:   "wrong variation used for flag test-flag",
+		},
+		{
+			name:        "offline",
+			cacheMock:   NewCacheMock(&flagv1.FlagData{}, nil),
+			offline:     true,
+			wantErr:     ErrOffline,
+			wantReason:  flag.ReasonOffline,
+			wantMessage: "go-feature-flag is offline, the default value is used for flag test-flag",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := &GoFeatureFlag{
+				cache:  tt.cacheMock,
+				config: Config{Offline: tt.offline},
+			}
+
+			_, err := g.BoolVariation("test-flag", ffuser.NewUser("random-key"), false)
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.EqualError(t, err, tt.wantMessage)
+
+			var evalErr *EvaluationError
+			assert.True(t, errors.As(err, &evalErr))
+			assert.Equal(t, "test-flag", evalErr.FlagKey)
+			assert.Equal(t, tt.wantReason, evalErr.Reason)
+			assert.Equal(t, tt.wantErrorCode, evalErr.ErrorCode)
+
+			// RawVariation accepts every type of value, the type mismatch cannot happen.
+			if tt.wantErr != ErrTypeMismatch {
+				_, err = g.RawVariation("test-flag", ffuser.NewUser("random-key"), false)
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+		})
+	}
+}
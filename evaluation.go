@@ -0,0 +1,90 @@
+package ffclient
+
+import (
+	"github.com/thomaspoignant/go-feature-flag/ffuser"
+	"github.com/thomaspoignant/go-feature-flag/internal/flag"
+)
+
+// flagReader gives access to the flags and segments used to evaluate a flag,
+// it is the cache or a snapshot of the cache.
+type flagReader interface {
+	GetFlag(key string) (flag.Flag, error)
+	GetSegments() (flag.Segments, error)
+}
+
+// maxPrerequisiteDepth is the maximum length of a chain of prerequisites. The cycles are rejected when
+// loading the flags, this limit protects the evaluation if a cycle has not been detected.
+const maxPrerequisiteDepth = 64
+
+// evaluate returns the value of the flag for the user.
+// Before evaluating the flag, we check that all its prerequisites are satisfied, if it is not
+// the case we serve the default variation of the flag.
+func (g *GoFeatureFlag) evaluate(
+	flagKey string, f flag.Flag, user ffuser.User,
+) (interface{}, flag.ResolutionDetails, error) {
+	return g.evaluateFrom(g.cache, flagKey, f, user)
+}
+
+// evaluateFrom returns the value of the flag for the user,
+// the prerequisites and the segments are read from the reader.
+// An error is returned if the chain of prerequisites is too long to be evaluated.
+func (g *GoFeatureFlag) evaluateFrom(
+	reader flagReader, flagKey string, f flag.Flag, user ffuser.User,
+) (interface{}, flag.ResolutionDetails, error) {
+	value, resolutionDetails, ok := g.evaluateAtDepth(reader, flagKey, f, user, 0)
+	if !ok {
+		return nil, flag.ResolutionDetails{Reason: flag.ReasonError}, newPrerequisiteDepthError(flagKey)
+	}
+	return value, resolutionDetails, nil
+}
+
+// evaluateAtDepth evaluates the flag used as a prerequisite at this depth of the chain of prerequisites,
+// it returns false if the chain is longer than maxPrerequisiteDepth.
+func (g *GoFeatureFlag) evaluateAtDepth(
+	reader flagReader, flagKey string, f flag.Flag, user ffuser.User, depth int,
+) (interface{}, flag.ResolutionDetails, bool) {
+	for _, prerequisite := range f.GetPrerequisites() {
+		satisfied, ok := g.isPrerequisiteSatisfied(reader, prerequisite, user, depth+1)
+		if !ok {
+			return nil, flag.ResolutionDetails{}, false
+		}
+		if !satisfied {
+			prerequisiteKey := prerequisite.Key
+			defaultVariation := f.GetDefaultVariation()
+			return f.GetVariationValue(defaultVariation), flag.ResolutionDetails{
+				Variant:         defaultVariation,
+				Reason:          flag.ReasonPrerequisiteFailed,
+				PrerequisiteKey: &prerequisiteKey,
+			}, true
+		}
+	}
+	value, resolutionDetails := f.Value(flagKey, user, g.evaluationContext(reader))
+	return value, resolutionDetails, true
+}
+
+// evaluationContext returns the context used to evaluate the flags.
+func (g *GoFeatureFlag) evaluationContext(reader flagReader) flag.EvaluationContext {
+	// the segments are nil if the cache is not initialised, in that case no user is part of a segment.
+	segments, _ := reader.GetSegments()
+	return flag.EvaluationContext{
+		Environment: g.config.Environment,
+		Segments:    segments,
+	}
+}
+
+// isPrerequisiteSatisfied is checking if the prerequisite flag is evaluated to the expected
+// variation for the user. A missing or disabled prerequisite flag is never satisfied.
+// The second value is false if the chain of prerequisites is longer than maxPrerequisiteDepth.
+func (g *GoFeatureFlag) isPrerequisiteSatisfied(
+	reader flagReader, prerequisite flag.Prerequisite, user ffuser.User, depth int,
+) (bool, bool) {
+	if depth > maxPrerequisiteDepth {
+		return false, false
+	}
+	f, err := getFlagFrom(reader, prerequisite.Key)
+	if err != nil {
+		return false, true
+	}
+	_, resolutionDetails, ok := g.evaluateAtDepth(reader, prerequisite.Key, f, user, depth)
+	return resolutionDetails.Variant == prerequisite.Variation, ok
+}
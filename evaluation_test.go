@@ -0,0 +1,128 @@
+package ffclient
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thomaspoignant/go-feature-flag/ffnotifier"
+	"github.com/thomaspoignant/go-feature-flag/ffuser"
+	"github.com/thomaspoignant/go-feature-flag/internal/cache"
+	"github.com/thomaspoignant/go-feature-flag/internal/flag"
+	flagv1 "github.com/thomaspoignant/go-feature-flag/internal/flagv1"
+	"github.com/thomaspoignant/go-feature-flag/testutils/testconvert"
+)
+
+func TestGoFeatureFlag_evaluatePrerequisites(t *testing.T) {
+	flagCache := cache.New(cache.NewNotificationService([]ffnotifier.Notifier{}))
+	defer flagCache.Close()
+	err := flagCache.UpdateCache([]byte(`new-checkout:
+  rule: key eq "random-key"
+  percentage: 100
+  true: true
+  false: false
+  default: false
+new-checkout-v2:
+  prerequisites:
+    - key: new-checkout
+      variation: "True"
+  percentage: 100
+  true: true
+  false: false
+  default: false
+missing-prerequisite:
+  prerequisites:
+    - key: unknown-flag
+      variation: "True"
+  percentage: 100
+  true: true
+  false: false
+  default: false
+`), "yaml")
+	assert.NoError(t, err)
+	g := GoFeatureFlag{cache: flagCache}
+
+	tests := []struct {
+		name        string
+		flagKey     string
+		user        ffuser.User
+		wantValue   interface{}
+		wantDetails flag.ResolutionDetails
+	}{
+		{
+			name:        "prerequisite satisfied",
+			flagKey:     "new-checkout-v2",
+			user:        ffuser.NewUser("random-key"),
+			wantValue:   true,
+			wantDetails: flag.ResolutionDetails{Variant: "True", Reason: flag.ReasonPercentage},
+		},
+		{
+			name:      "prerequisite not satisfied",
+			flagKey:   "new-checkout-v2",
+			user:      ffuser.NewUser("other-key"),
+			wantValue: false,
+			wantDetails: flag.ResolutionDetails{
+				Variant:         "Default",
+				Reason:          flag.ReasonPrerequisiteFailed,
+				PrerequisiteKey: testconvert.String("new-checkout"),
+			},
+		},
+		{
+			name:      "missing prerequisite",
+			flagKey:   "missing-prerequisite",
+			user:      ffuser.NewUser("random-key"),
+			wantValue: false,
+			wantDetails: flag.ResolutionDetails{
+				Variant:         "Default",
+				Reason:          flag.ReasonPrerequisiteFailed,
+				PrerequisiteKey: testconvert.String("unknown-flag"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := g.getFlagFromCache(tt.flagKey)
+			assert.NoError(t, err)
+			value, details, err := g.evaluate(tt.flagKey, f, tt.user)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantValue, value)
+			assert.Equal(t, tt.wantDetails, details)
+		})
+	}
+}
+
+// mapReader is a flagReader without any check on the flags, it allows to have a cycle in the prerequisites.
+type mapReader map[string]flag.Flag
+
+func (m mapReader) GetFlag(key string) (flag.Flag, error) {
+	return m[key], nil
+}
+
+func (m mapReader) GetSegments() (flag.Segments, error) {
+	return flag.Segments{}, nil
+}
+
+func TestGoFeatureFlag_evaluatePrerequisitesCycle(t *testing.T) {
+	reader := mapReader{
+		"flag-a": &flagv1.FlagData{
+			Prerequisites: []flag.Prerequisite{{Key: "flag-b", Variation: "True"}},
+			Percentage:    testconvert.Float64(100),
+			True:          testconvert.Interface(true),
+			False:         testconvert.Interface(false),
+			Default:       testconvert.Interface(false),
+		},
+		"flag-b": &flagv1.FlagData{
+			Prerequisites: []flag.Prerequisite{{Key: "flag-a", Variation: "True"}},
+			Percentage:    testconvert.Float64(100),
+			True:          testconvert.Interface(true),
+			False:         testconvert.Interface(false),
+			Default:       testconvert.Interface(false),
+		},
+	}
+	g := GoFeatureFlag{}
+
+	value, details, err := g.evaluateFrom(reader, "flag-a", reader["flag-a"], ffuser.NewUser("random-key"))
+	assert.EqualError(t, err,
+		"impossible to evaluate flag flag-a, its chain of prerequisites is too long or circular")
+	assert.Nil(t, value)
+	assert.Equal(t, flag.ReasonError, details.Reason)
+}
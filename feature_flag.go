@@ -1,8 +1,10 @@
 package ffclient
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
 	"time"
 
@@ -36,10 +38,14 @@ func Close() {
 // GoFeatureFlag is the main object of the library
 // it contains the cache, the config and the update.
 type GoFeatureFlag struct {
-	cache        cache.Manager
-	config       Config
-	bgUpdater    backgroundUpdater
-	dataExporter *exporter.DataExporterScheduler
+	cache               cache.Manager
+	config              Config
+	bgUpdater           backgroundUpdater
+	dataExporter        *exporter.DataExporterScheduler
+	notificationService cache.Service
+
+	valueListenersMutex sync.Mutex
+	valueListeners      map[*cache.Subscription]*valueListener
 }
 
 // ff is the default object for go-feature-flag
@@ -63,6 +69,15 @@ func New(config Config) (*GoFeatureFlag, error) {
 		// do nothing
 	}
 
+	if _, ok := config.Retriever.(*AllFlagsStateRetriever); ok {
+		// the AllFlagsStateRetriever generates JSON, decoding it as YAML would convert the whole number floats to int.
+		if config.FileFormat != "" && !strings.EqualFold(config.FileFormat, "json") {
+			return nil, fmt.Errorf("%s is not a valid FileFormat for the AllFlagsStateRetriever, it should be json",
+				config.FileFormat)
+		}
+		config.FileFormat = "json"
+	}
+
 	goFF := &GoFeatureFlag{
 		config: config,
 	}
@@ -72,11 +87,23 @@ func New(config Config) (*GoFeatureFlag, error) {
 		if err != nil {
 			return nil, fmt.Errorf("wrong configuration in your webhook: %v", err)
 		}
-		notificationService := cache.NewNotificationService(notifiers)
+		goFF.notificationService = cache.NewNotificationService(notifiers)
 		goFF.bgUpdater = newBackgroundUpdater(config.PollingInterval)
-		goFF.cache = cache.New(notificationService)
+		goFF.cache = cache.NewWithUpdateGuard(goFF.notificationService, cache.UpdateGuard{
+			MaxDeletedFlagsPercentage: config.UpdateGuard.MaxDeletedFlagsPercentage,
+			RefuseEmptyFlagFile:       config.UpdateGuard.RefuseEmptyFlagFile,
+		})
 
 		err = retrieveFlagsAndUpdateCache(goFF.config, goFF.cache)
+		if err != nil && config.PersistentFlagConfigurationFile != "" {
+			// the retriever is unreachable, we start with the last flags retrieved successfully.
+			if errPersisted := loadPersistedFlags(goFF.config, goFF.cache); errPersisted == nil {
+				err = nil
+			} else {
+				fflog.Printf(config.Logger, "error: impossible to load the persistent flag configuration file: %v\n",
+					errPersisted)
+			}
+		}
 		if err != nil && !config.StartWithRetrieverError {
 			return nil, fmt.Errorf("impossible to retrieve the flags, please check your configuration: %v", err)
 		}
@@ -111,6 +138,7 @@ func (g *GoFeatureFlag) Close() {
 		if g.dataExporter != nil {
 			g.dataExporter.Close()
 		}
+		g.closeValueListeners()
 	}
 }
 
@@ -123,6 +151,8 @@ func (g *GoFeatureFlag) startFlagUpdaterDaemon() {
 			if err != nil {
 				fflog.Printf(g.config.Logger, "error while updating the cache: %v\n", err)
 			}
+			// the values can change even if the flags are the same (ex: progressive rollout).
+			g.notifyValueChanges()
 		case <-g.bgUpdater.updaterChan:
 			return
 		}
@@ -130,7 +160,7 @@ func (g *GoFeatureFlag) startFlagUpdaterDaemon() {
 }
 
 // retrieveFlagsAndUpdateCache is called every X seconds to refresh the cache flag.
-func retrieveFlagsAndUpdateCache(config Config, cache cache.Manager) error {
+func retrieveFlagsAndUpdateCache(config Config, flagCache cache.Manager) error {
 	retriever, err := config.GetRetriever()
 	if err != nil {
 		log.Printf("error while getting the file retriever: %v", err)
@@ -143,11 +173,27 @@ func retrieveFlagsAndUpdateCache(config Config, cache cache.Manager) error {
 		return err
 	}
 
-	err = cache.UpdateCache(loadedFlags, config.FileFormat)
-	if err != nil {
+	previousUpdate := flagCache.GetLatestUpdateDate()
+	err = flagCache.UpdateCache(loadedFlags, config.FileFormat)
+	if errors.Is(err, cache.ErrInvalidRules) {
+		// the flags are loaded, only the rules that cannot be parsed never match.
+		log.Printf("warning: %v", err)
+	} else if errors.Is(err, cache.ErrUpdateRefused) {
+		// the flags in the cache are kept, the rejection is reported with the logger of the configuration.
+		fflog.Printf(config.Logger, "error: %v\n", err)
+		return err
+	} else if err != nil {
 		log.Printf("error: impossible to update the cache of the flags: %v", err)
 		return err
 	}
+
+	if config.PersistentFlagConfigurationFile != "" && !flagCache.GetLatestUpdateDate().Equal(previousUpdate) {
+		// the file is written only if the flags have changed,
+		// a failure to persist the flags should not prevent the flags to be served.
+		if err := persistFlags(config.PersistentFlagConfigurationFile, loadedFlags, config.FileFormat); err != nil {
+			fflog.Printf(config.Logger, "error: impossible to write the persistent flag configuration file: %v\n", err)
+		}
+	}
 	return nil
 }
 
@@ -163,3 +209,18 @@ func (g *GoFeatureFlag) GetCacheRefreshDate() time.Time {
 func GetCacheRefreshDate() time.Time {
 	return ff.GetCacheRefreshDate()
 }
+
+// GetCacheCheckDate gives the date of the latest check of the flag file,
+// the cache is refreshed only if the flag file has changed since the previous check.
+func (g *GoFeatureFlag) GetCacheCheckDate() time.Time {
+	if g.config.Offline {
+		return time.Time{}
+	}
+	return g.cache.GetLatestCheckDate()
+}
+
+// GetCacheCheckDate gives the date of the latest check of the flag file,
+// the cache is refreshed only if the flag file has changed since the previous check.
+func GetCacheCheckDate() time.Time {
+	return ff.GetCacheCheckDate()
+}
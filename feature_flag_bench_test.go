@@ -47,6 +47,36 @@ func BenchmarkAllFlags(b *testing.B) {
 	}
 }
 
+func BenchmarkAllFlags_Parallel(b *testing.B) {
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		user := ffuser.NewUser("user-1")
+		for pb.Next() {
+			_ = client.AllFlagsState(user)
+		}
+	})
+}
+
+func BenchmarkBoolVar_NoRule100_Parallel(b *testing.B) {
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		user := ffuser.NewUser("user-1")
+		for pb.Next() {
+			_, _ = client.BoolVariation("bool-no-rule-100", user, false)
+		}
+	})
+}
+
+func BenchmarkStringVar_Rule_Parallel(b *testing.B) {
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		user := ffuser.NewUser("user-1")
+		for pb.Next() {
+			_, _ = client.StringVariation("string-rule", user, "error")
+		}
+	})
+}
+
 func BenchmarkBoolVar_NoRule100(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		user := ffuser.NewUser(fmt.Sprintf("user-%d", i))
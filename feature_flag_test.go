@@ -64,6 +64,7 @@ func TestValidUseCase(t *testing.T) {
 	hasUnknownFlag, _ := ffclient.BoolVariation("unknown-flag", user, false)
 	assert.False(t, hasUnknownFlag, "User should use default value if flag does not exists")
 	assert.NotEqual(t, time.Time{}, ffclient.GetCacheRefreshDate())
+	assert.NotEqual(t, time.Time{}, ffclient.GetCacheCheckDate())
 
 	allFlags := ffclient.AllFlagsState(user)
 	assert.Equal(t, 2, len(allFlags.GetFlags()))
@@ -163,6 +164,95 @@ func Test2GoFeatureFlagInstance(t *testing.T) {
 	assert.False(t, hasTestFlagClient2, "User should have test flag")
 }
 
+func TestValidUseCaseMultiVariation(t *testing.T) {
+	gffClient, err := ffclient.New(ffclient.Config{
+		PollingInterval: 5 * time.Second,
+		Retriever:       &ffclient.FileRetriever{Path: "testdata/ffclient/multi_variation/flag-config.yaml"},
+		Logger:          log.New(os.Stdout, "", 0),
+	})
+	defer gffClient.Close()
+	assert.NoError(t, err)
+
+	hasTestFlag, _ := gffClient.BoolVariation("test-flag", ffuser.NewUser("random-key"), false)
+	assert.True(t, hasTestFlag, "flagv1 flag should still be available")
+
+	color, _ := gffClient.StringVariation("color-flag", ffuser.NewUser("other-key"), "black")
+	assert.Equal(t, "red", color)
+
+	color, _ = gffClient.StringVariation("color-flag", ffuser.NewUser("random-key"), "black")
+	assert.Equal(t, "green", color)
+
+	betaUser := ffuser.NewUserBuilder("random-key").AddCustom("beta", true).Build()
+	color, _ = gffClient.StringVariation("color-flag", betaUser, "black")
+	assert.Equal(t, "blue", color)
+}
+
+func TestValidUseCasePrerequisites(t *testing.T) {
+	gffClient, err := ffclient.New(ffclient.Config{
+		PollingInterval: 5 * time.Second,
+		Retriever:       &ffclient.FileRetriever{Path: "testdata/ffclient/prerequisites/flag-config.yaml"},
+		Logger:          log.New(os.Stdout, "", 0),
+	})
+	defer gffClient.Close()
+	assert.NoError(t, err)
+
+	user := ffuser.NewUser("random-key")
+	otherUser := ffuser.NewUser("other-key")
+
+	hasFlag, _ := gffClient.BoolVariation("new-checkout-v2", user, false)
+	assert.True(t, hasFlag, "prerequisite is satisfied")
+	hasFlag, _ = gffClient.BoolVariation("new-checkout-v2", otherUser, true)
+	assert.False(t, hasFlag, "prerequisite is not satisfied, we should serve the default value")
+
+	color, _ := gffClient.StringVariation("checkout-color", user, "black")
+	assert.Equal(t, "red", color)
+	color, _ = gffClient.StringVariation("checkout-color", otherUser, "black")
+	assert.Equal(t, "blue", color, "prerequisites are evaluated recursively")
+
+	hasFlag, _ = gffClient.BoolVariation("depends-on-disabled-flag", user, true)
+	assert.False(t, hasFlag, "a disabled prerequisite is never satisfied")
+}
+
+func TestPrerequisitesCycleInScheduledStep(t *testing.T) {
+	_, err := ffclient.New(ffclient.Config{
+		PollingInterval: 5 * time.Second,
+		Retriever:       &ffclient.FileRetriever{Path: "testdata/ffclient/prerequisites/scheduled-cycle.yaml"},
+	})
+	assert.ErrorContains(t, err, "circular dependency in the flag prerequisites: flag-a -> flag-b -> flag-a")
+}
+
+func TestValidUseCaseSegments(t *testing.T) {
+	gffClient, err := ffclient.New(ffclient.Config{
+		PollingInterval: 5 * time.Second,
+		Retriever:       &ffclient.FileRetriever{Path: "testdata/ffclient/segments/flag-config.yaml"},
+		Logger:          log.New(os.Stdout, "", 0),
+	})
+	defer gffClient.Close()
+	assert.NoError(t, err)
+
+	user := ffuser.NewUser("random-key")
+	betaUser := ffuser.NewUserBuilder("other-key").AddCustom("beta", true).Build()
+	internalUser := ffuser.NewUserBuilder("internal-key").
+		AddCustom("beta", true).
+		AddCustom("email", "john.doe@example.com").
+		Build()
+
+	hasFlag, _ := gffClient.BoolVariation("new-checkout", user, false)
+	assert.True(t, hasFlag, "user is in the segment by key")
+	hasFlag, _ = gffClient.BoolVariation("new-checkout", betaUser, false)
+	assert.True(t, hasFlag, "user is in the segment by query")
+	hasFlag, _ = gffClient.BoolVariation("new-checkout", internalUser, true)
+	assert.False(t, hasFlag, "user is in an excluded segment")
+
+	color, _ := gffClient.StringVariation("checkout-color", internalUser, "black")
+	assert.Equal(t, "red", color)
+	color, _ = gffClient.StringVariation("checkout-color", user, "black")
+	assert.Equal(t, "blue", color)
+
+	_, err = gffClient.BoolVariation("segments", user, false)
+	assert.Error(t, err, "segments is not a flag")
+}
+
 func TestUpdateFlag(t *testing.T) {
 	initialFileContent := `test-flag:
   rule: key eq "random-key"
@@ -286,6 +376,64 @@ func TestFlagFileUnreachable(t *testing.T) {
 	assert.Equal(t, "true", flagValue, "should use the true value")
 }
 
+func TestUpdateGuardKeepsTheFlags(t *testing.T) {
+	flagFile, _ := ioutil.TempFile("", "")
+	defer os.Remove(flagFile.Name())
+	_ = ioutil.WriteFile(flagFile.Name(), []byte(`test-flag:
+  percentage: 100
+  true: "true"
+  false: "false"
+  default: "false"`), 0o600)
+	logFile, _ := ioutil.TempFile("", "")
+	defer os.Remove(logFile.Name())
+
+	gff, err := ffclient.New(ffclient.Config{
+		PollingInterval: 1 * time.Second,
+		Retriever:       &ffclient.FileRetriever{Path: flagFile.Name()},
+		UpdateGuard:     ffclient.UpdateGuard{RefuseEmptyFlagFile: true},
+		Logger:          log.New(logFile, "", 0),
+	})
+	assert.NoError(t, err)
+	defer gff.Close()
+
+	// a bad deploy truncates the flag file.
+	_ = ioutil.WriteFile(flagFile.Name(), []byte(``), 0o600)
+	time.Sleep(2 * time.Second)
+
+	flagValue, _ := gff.StringVariation("test-flag", ffuser.NewUser("random-key"), "SDKdefault")
+	assert.Equal(t, "true", flagValue, "should keep the flags before the refused update")
+
+	// the rejection is logged with the logger of the configuration.
+	content, _ := ioutil.ReadFile(logFile.Name())
+	assert.Contains(t, string(content), "update of the flag file refused")
+}
+
+func TestInvalidRulesDoNotPreventTheLoading(t *testing.T) {
+	gff, err := ffclient.New(ffclient.Config{
+		PollingInterval: 5 * time.Second,
+		Retriever:       &ffclient.FileRetriever{Path: "testdata/ffclient/invalid_rules/flag-config.yaml"},
+	})
+	assert.NoError(t, err)
+	defer gff.Close()
+
+	user := ffuser.NewUser("random-key")
+	hasValidFlag, err := gff.BoolVariation("valid-flag", user, false)
+	assert.NoError(t, err)
+	assert.True(t, hasValidFlag, "the flags without invalid rules should work")
+
+	// the rules that cannot be parsed never match.
+	hasInvalidRuleFlag, err := gff.BoolVariation("invalid-rule-flag", user, true)
+	assert.NoError(t, err)
+	assert.False(t, hasInvalidRuleFlag)
+
+	got, err := gff.StringVariation("invalid-targeting-flag", ffuser.NewUser("a"), "SDKdefault")
+	assert.NoError(t, err)
+	assert.Equal(t, "A", got)
+	got, err = gff.StringVariation("invalid-targeting-flag", ffuser.NewUser("teen-key"), "SDKdefault")
+	assert.NoError(t, err)
+	assert.Equal(t, "B", got, "the keys of a segment with an invalid query still match")
+}
+
 func TestValidUseCaseBigFlagFile(t *testing.T) {
 	// Valid use case
 	gff, err := ffclient.New(ffclient.Config{
@@ -340,14 +488,18 @@ func TestGoFeatureFlag_GetCacheRefreshDate(t *testing.T) {
 			})
 
 			date1 := gff.GetCacheRefreshDate()
+			checkDate1 := gff.GetCacheCheckDate()
 			time.Sleep(tt.fields.waitingDuration)
 			date2 := gff.GetCacheRefreshDate()
+			checkDate2 := gff.GetCacheCheckDate()
 
 			if !tt.offline {
 				assert.NotEqual(t, time.Time{}, date1)
-				assert.NotEqual(t, time.Time{}, date2)
+				assert.NotEqual(t, time.Time{}, checkDate1)
 			}
-			assert.Equal(t, tt.hasRefresh, date1.Before(date2))
+			// the flag file does not change, so the flags are checked but never reloaded.
+			assert.Equal(t, tt.hasRefresh, checkDate1.Before(checkDate2))
+			assert.Equal(t, date1, date2)
 		})
 	}
 }
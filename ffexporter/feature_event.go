@@ -54,6 +54,7 @@ type FeatureEvent struct {
 	// Variation  of the flag requested. Flag variation values can be "True", "False", "Default" or "SdkDefault"
 	// depending on which value was taken during flag evaluation. "SdkDefault" is used when an error is detected and the
 	// default value passed during the call to your variation is used.
+	// For flags using the multi-variation format, it is the name of the variation served (the arm of the experiment).
 	Variation string `json:"variation"`
 
 	// Value of the feature flag returned by feature flag evaluation.
@@ -10,14 +10,24 @@ type DiffCache struct {
 	Deleted map[string]flag.Flag   `json:"deleted"`
 	Added   map[string]flag.Flag   `json:"added"`
 	Updated map[string]DiffUpdated `json:"updated"`
+
+	DeletedSegments map[string]flag.Segment       `json:"deleted_segments,omitempty"`
+	AddedSegments   map[string]flag.Segment       `json:"added_segments,omitempty"`
+	UpdatedSegments map[string]DiffSegmentUpdated `json:"updated_segments,omitempty"`
 }
 
 // HasDiff check if we have differences
 func (d *DiffCache) HasDiff() bool {
-	return len(d.Deleted) > 0 || len(d.Added) > 0 || len(d.Updated) > 0
+	return len(d.Deleted) > 0 || len(d.Added) > 0 || len(d.Updated) > 0 ||
+		len(d.DeletedSegments) > 0 || len(d.AddedSegments) > 0 || len(d.UpdatedSegments) > 0
 }
 
 type DiffUpdated struct {
 	Before flag.Flag `json:"old_value"`
 	After  flag.Flag `json:"new_value"`
 }
+
+type DiffSegmentUpdated struct {
+	Before flag.Segment `json:"old_value"`
+	After  flag.Segment `json:"new_value"`
+}
@@ -7,3 +7,18 @@ import (
 type Notifier interface {
 	Notify(cache DiffCache, waitGroup *sync.WaitGroup)
 }
+
+// RejectionNotifier is implemented by the notifiers that want to be informed when an update of the
+// flag file is refused, the flags in the cache are not changed when an update is refused.
+type RejectionNotifier interface {
+	NotifyRejection(rejection UpdateRejection, waitGroup *sync.WaitGroup)
+}
+
+// UpdateRejection describes an update of the flag file refused by go-feature-flag.
+type UpdateRejection struct {
+	// Reason explains why the update has been refused.
+	Reason string `json:"reason"`
+
+	// DeletedFlags is the list of flags that the update would have deleted.
+	DeletedFlags []string `json:"deleted_flags,omitempty"`
+}
@@ -0,0 +1,132 @@
+package ffclient
+
+import (
+	"context"
+
+	"github.com/thomaspoignant/go-feature-flag/ffuser"
+	"github.com/thomaspoignant/go-feature-flag/internal/flag"
+	"github.com/thomaspoignant/go-feature-flag/internal/model"
+)
+
+// HookContext contains the information about the evaluated flag, it is passed to every stage of the hooks.
+type HookContext struct {
+	// FlagKey is the key of the flag evaluated.
+	FlagKey string
+
+	// User is the user used for the evaluation.
+	User ffuser.User
+
+	// DefaultValue is the default value passed to the variation function.
+	DefaultValue interface{}
+}
+
+// Hook allows to run code around the evaluation of a flag (tracing, metrics, logging, validation ...).
+// The hooks are registered in Config.Hooks, the stage Before is called in the order of registration
+// and the other stages are called in the reverse order.
+type Hook interface {
+	// Before is called before the evaluation of the flag, the context returned is passed to the next stages.
+	// If an error is returned, the flag is not evaluated and the default value is served.
+	Before(ctx context.Context, hookCtx HookContext) (context.Context, error)
+
+	// After is called when the flag has been evaluated successfully.
+	// If an error is returned, the default value is served instead of the value of the flag.
+	After(ctx context.Context, hookCtx HookContext, value interface{}, details EvaluationDetails) error
+
+	// Error is called when the default value is served because of an error.
+	Error(ctx context.Context, hookCtx HookContext, err error)
+
+	// Finally is called at the end of every evaluation.
+	Finally(ctx context.Context, hookCtx HookContext)
+}
+
+// BaseHook is a Hook doing nothing, embed it in your hook to implement only the stages you need.
+type BaseHook struct{}
+
+func (BaseHook) Before(ctx context.Context, _ HookContext) (context.Context, error) {
+	return ctx, nil
+}
+
+func (BaseHook) After(_ context.Context, _ HookContext, _ interface{}, _ EvaluationDetails) error {
+	return nil
+}
+
+func (BaseHook) Error(_ context.Context, _ HookContext, _ error) {}
+
+func (BaseHook) Finally(_ context.Context, _ HookContext) {}
+
+// evaluateWithHooks runs the hooks of the configuration around the evaluation of a flag.
+func evaluateWithHooks[T any](ctx context.Context, g *GoFeatureFlag, flagKey string, user ffuser.User,
+	sdkDefaultValue T, evaluate func() (T, model.VariationResult, error),
+) (T, model.VariationResult, error) {
+	hooks := g.config.Hooks
+	if len(hooks) == 0 {
+		return evaluate()
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	hookCtx := HookContext{FlagKey: flagKey, User: user, DefaultValue: sdkDefaultValue}
+	ctx, err := runBeforeHooks(ctx, hooks, hookCtx)
+	defer runFinallyHooks(ctx, hooks, hookCtx)
+	if err != nil {
+		err = newHookError(flagKey, err)
+		runErrorHooks(ctx, hooks, hookCtx, err)
+		// the flag is not evaluated, but the evaluation is exported like the other errors if the flag tracks it.
+		var f flag.Flag
+		if !g.config.Offline {
+			f, _ = g.getFlagFromCache(flagKey)
+		}
+		return sdkDefaultValue, computeErrorVariationResult(f, err), err
+	}
+
+	value, varResult, err := evaluate()
+	if err != nil {
+		runErrorHooks(ctx, hooks, hookCtx, err)
+		return value, varResult, err
+	}
+
+	if err := runAfterHooks(ctx, hooks, hookCtx, value, newEvaluationDetails(varResult)); err != nil {
+		err = newHookError(flagKey, err)
+		runErrorHooks(ctx, hooks, hookCtx, err)
+		errResult := computeErrorVariationResult(nil, err)
+		errResult.TrackEvents, errResult.Version = varResult.TrackEvents, varResult.Version
+		return sdkDefaultValue, errResult, err
+	}
+	return value, varResult, nil
+}
+
+func runBeforeHooks(ctx context.Context, hooks []Hook, hookCtx HookContext) (context.Context, error) {
+	for _, hook := range hooks {
+		hookResultCtx, err := hook.Before(ctx, hookCtx)
+		if err != nil {
+			return ctx, err
+		}
+		if hookResultCtx != nil {
+			ctx = hookResultCtx
+		}
+	}
+	return ctx, nil
+}
+
+func runAfterHooks(ctx context.Context, hooks []Hook, hookCtx HookContext, value interface{},
+	details EvaluationDetails) error {
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i].After(ctx, hookCtx, value, details); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runErrorHooks(ctx context.Context, hooks []Hook, hookCtx HookContext, err error) {
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i].Error(ctx, hookCtx, err)
+	}
+}
+
+func runFinallyHooks(ctx context.Context, hooks []Hook, hookCtx HookContext) {
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i].Finally(ctx, hookCtx)
+	}
+}
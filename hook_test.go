@@ -0,0 +1,199 @@
+package ffclient_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	ffclient "github.com/thomaspoignant/go-feature-flag"
+	"github.com/thomaspoignant/go-feature-flag/ffuser"
+)
+
+type ctxKey string
+
+// recordHook records the stages called, the context value set by the first hook is recorded too.
+type recordHook struct {
+	ffclient.BaseHook
+	name      string
+	stages    *[]string
+	beforeErr error
+	afterErr  error
+}
+
+func (h recordHook) Before(ctx context.Context, hookCtx ffclient.HookContext) (context.Context, error) {
+	*h.stages = append(*h.stages, h.name+".before:"+hookCtx.FlagKey)
+	if h.beforeErr != nil {
+		return ctx, h.beforeErr
+	}
+	return context.WithValue(ctx, ctxKey(h.name), "span-"+h.name), nil
+}
+
+func (h recordHook) After(ctx context.Context, _ ffclient.HookContext, value interface{},
+	details ffclient.EvaluationDetails) error {
+	*h.stages = append(*h.stages, h.name+".after:"+value.(string)+":"+details.VariationName+":"+
+		ctx.Value(ctxKey(h.name)).(string))
+	return h.afterErr
+}
+
+func (h recordHook) Error(_ context.Context, _ ffclient.HookContext, err error) {
+	*h.stages = append(*h.stages, h.name+".error:"+err.Error())
+}
+
+func (h recordHook) Finally(_ context.Context, _ ffclient.HookContext) {
+	*h.stages = append(*h.stages, h.name+".finally")
+}
+
+func TestHooks(t *testing.T) {
+	errValidation := errors.New("invalid color")
+	errBefore := errors.New("no span available")
+	tests := []struct {
+		name       string
+		flagKey    string
+		beforeErr  error
+		afterErr   error
+		want       string
+		wantErr    error
+		wantStages []string
+	}{
+		{
+			name:    "successful evaluation",
+			flagKey: "checkout-color",
+			want:    "blue",
+			wantStages: []string{
+				"first.before:checkout-color",
+				"second.before:checkout-color",
+				"second.after:blue:blue:span-second",
+				"first.after:blue:blue:span-first",
+				"second.finally",
+				"first.finally",
+			},
+		},
+		{
+			name:    "flag not found",
+			flagKey: "unknown-flag",
+			want:    "black",
+			wantErr: ffclient.ErrFlagNotFound,
+			wantStages: []string{
+				"first.before:unknown-flag",
+				"second.before:unknown-flag",
+				"second.error:flag unknown-flag is not present or disabled",
+				"first.error:flag unknown-flag is not present or disabled",
+				"second.finally",
+				"first.finally",
+			},
+		},
+		{
+			name:      "error in before stage",
+			flagKey:   "checkout-color",
+			beforeErr: errBefore,
+			want:      "black",
+			wantErr:   errBefore,
+			wantStages: []string{
+				"first.before:checkout-color",
+				"second.before:checkout-color",
+				"second.error:hook failed for flag checkout-color: no span available",
+				"first.error:hook failed for flag checkout-color: no span available",
+				"second.finally",
+				"first.finally",
+			},
+		},
+		{
+			name:     "error in after stage",
+			flagKey:  "checkout-color",
+			afterErr: errValidation,
+			want:     "black",
+			wantErr:  errValidation,
+			wantStages: []string{
+				"first.before:checkout-color",
+				"second.before:checkout-color",
+				"second.after:blue:blue:span-second",
+				"second.error:hook failed for flag checkout-color: invalid color",
+				"first.error:hook failed for flag checkout-color: invalid color",
+				"second.finally",
+				"first.finally",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stages []string
+			gffClient, err := ffclient.New(ffclient.Config{
+				PollingInterval: 5 * time.Second,
+				Retriever:       &ffclient.FileRetriever{Path: "testdata/ffclient/variation_details/flag-config.yaml"},
+				Hooks: []ffclient.Hook{
+					recordHook{name: "first", stages: &stages},
+					recordHook{name: "second", stages: &stages, beforeErr: tt.beforeErr, afterErr: tt.afterErr},
+				},
+			})
+			assert.NoError(t, err)
+			defer gffClient.Close()
+
+			got, err := gffClient.StringVariationWithContext(
+				context.Background(), tt.flagKey, ffuser.NewUser("random-key"), "black")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantStages, stages)
+		})
+	}
+}
+
+func TestHooksContext(t *testing.T) {
+	var received interface{}
+	gffClient, err := ffclient.New(ffclient.Config{
+		PollingInterval: 5 * time.Second,
+		Retriever:       &ffclient.FileRetriever{Path: "testdata/ffclient/variation_details/flag-config.yaml"},
+		Hooks:           []ffclient.Hook{contextHook{received: &received}},
+	})
+	assert.NoError(t, err)
+	defer gffClient.Close()
+
+	ctx := context.WithValue(context.Background(), ctxKey("request-id"), "1234")
+	_, err = gffClient.BoolVariationWithContext(ctx, "new-checkout", ffuser.NewUser("random-key"), false)
+	assert.NoError(t, err)
+	assert.Equal(t, "1234", received)
+
+	received = nil
+	_, err = gffClient.StringVariationDetailsWithContext(ctx, "checkout-color", ffuser.NewUser("random-key"), "black")
+	assert.NoError(t, err)
+	assert.Equal(t, "1234", received)
+
+	_, err = gffClient.RawVariation("new-checkout", ffuser.NewUser("random-key"), false)
+	assert.NoError(t, err)
+	assert.Nil(t, received, "RawVariation is called with an empty context")
+}
+
+func TestHooksBeforeErrorKeepsTrackEvents(t *testing.T) {
+	var stages []string
+	gffClient, err := ffclient.New(ffclient.Config{
+		PollingInterval: 5 * time.Second,
+		Retriever:       &ffclient.FileRetriever{Path: "testdata/ffclient/variation_details/flag-config.yaml"},
+		Hooks: []ffclient.Hook{
+			recordHook{name: "first", stages: &stages, beforeErr: errors.New("no span available")},
+		},
+	})
+	assert.NoError(t, err)
+	defer gffClient.Close()
+
+	details, err := gffClient.StringVariationDetailsWithContext(
+		context.Background(), "checkout-color", ffuser.NewUser("random-key"), "black")
+	assert.Error(t, err)
+	assert.Equal(t, "black", details.Value)
+	assert.Equal(t, ffclient.ReasonError, details.Reason)
+	assert.True(t, details.TrackEvents, "the evaluation is exported as the flag tracks the events")
+}
+
+// contextHook records the request-id of the context received in the Finally stage.
+type contextHook struct {
+	ffclient.BaseHook
+	received *interface{}
+}
+
+func (h contextHook) Finally(ctx context.Context, _ ffclient.HookContext) {
+	*h.received = ctx.Value(ctxKey("request-id"))
+}
@@ -1,17 +1,13 @@
 package cache
 
 import (
-	"encoding/json"
-	"errors"
-	"strings"
+	"crypto/sha256"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/thomaspoignant/go-feature-flag/ffnotifier"
 	"github.com/thomaspoignant/go-feature-flag/internal/flag"
-	flagv1 "github.com/thomaspoignant/go-feature-flag/internal/flagv1"
-	"gopkg.in/yaml.v3"
-
-	"github.com/pelletier/go-toml"
 )
 
 type Manager interface {
@@ -19,89 +15,209 @@ type Manager interface {
 	Close()
 	GetFlag(key string) (flag.Flag, error)
 	AllFlags() (map[string]flag.Flag, error)
+	GetSegments() (flag.Segments, error)
 	GetLatestUpdateDate() time.Time
+	GetLatestCheckDate() time.Time
+	Snapshot() (Snapshot, error)
 }
 
+// cacheManagerImpl keeps the flags in an immutable Snapshot replaced atomically at every update,
+// the readers never take a lock.
 type cacheManagerImpl struct {
-	inMemoryCache       Cache
-	mutex               sync.RWMutex
+	// latestCheck is the date (in nanoseconds) of the latest call to UpdateCache, even if the flags did not change.
+	// It is the first field to be 64-bit aligned for atomic operations.
+	latestCheck int64
+
+	// snapshot contains a *Snapshot, it is nil once the cache is closed.
+	snapshot            atomic.Value
 	notificationService Service
-	latestUpdate        time.Time
+	updateGuard         UpdateGuard
+
+	// rejectedHash is the hash of the latest flag file refused, it is refused again
+	// without notifying the rejection until the flag file changes.
+	rejectedMutex sync.Mutex
+	rejectedHash  [sha256.Size]byte
+	rejectedErr   error
 }
 
 func New(notificationService Service) Manager {
-	return &cacheManagerImpl{
-		inMemoryCache:       NewInMemoryCache(),
-		mutex:               sync.RWMutex{},
+	return NewWithUpdateGuard(notificationService, UpdateGuard{})
+}
+
+// NewWithUpdateGuard creates a cache refusing the updates of the flag file rejected by the UpdateGuard.
+func NewWithUpdateGuard(notificationService Service, updateGuard UpdateGuard) Manager {
+	c := &cacheManagerImpl{
 		notificationService: notificationService,
+		updateGuard:         updateGuard,
 	}
+	emptySnapshot := NewSnapshot(nil, nil)
+	emptySnapshot.latestUpdate = time.Time{}
+	c.snapshot.Store(&emptySnapshot)
+	return c
 }
 
+// UpdateCache loads the flag file in the cache and notifies the changes.
+// If the content of the file is the same as the one in the cache, the cache is not rebuilt
+// and only the steps of the scheduled rollouts reached since the previous update are notified.
+// An invalid flag file or an update refused by the UpdateGuard keeps the flags in the cache,
+// and the rejection is sent to the notifiers.
+// A flag file with rules that cannot be parsed is loaded, these rules never match and
+// the returned error matches ErrInvalidRules.
 func (c *cacheManagerImpl) UpdateCache(loadedFlags []byte, fileFormat string) error {
-	var newFlags map[string]flagv1.FlagData
-	var err error
-	switch strings.ToLower(fileFormat) {
-	case "toml":
-		err = toml.Unmarshal(loadedFlags, &newFlags)
-	case "json":
-		err = json.Unmarshal(loadedFlags, &newFlags)
-	default:
-		// default unmarshaller is YAML
-		err = yaml.Unmarshal(loadedFlags, &newFlags)
+	atomic.StoreInt64(&c.latestCheck, time.Now().UnixNano())
+	hash := contentHash(loadedFlags, fileFormat)
+	current := c.currentSnapshot()
+	if current != nil && current.hash == hash {
+		c.notifyScheduledSteps(current)
+		return nil
 	}
-	if err != nil {
+	if err := c.previousRejection(hash); err != nil {
 		return err
 	}
 
-	newCache := NewInMemoryCache()
-	newCache.Init(newFlags)
-	newCacheFlags := newCache.All()
-	oldCacheFlags := map[string]flag.Flag{}
+	newFlags, newSegments, warnings, err := unmarshalFlags(loadedFlags, fileFormat)
+	if err != nil {
+		return c.reject(hash, err, ffnotifier.UpdateRejection{Reason: err.Error()})
+	}
+
+	if err := checkPrerequisites(newFlags); err != nil {
+		return c.reject(hash, err, ffnotifier.UpdateRejection{Reason: err.Error()})
+	}
 
-	c.mutex.Lock()
-	// collect flags for compare.
-	if c.inMemoryCache != nil {
-		oldCacheFlags = c.inMemoryCache.All()
+	currentFlags := map[string]flag.Flag{}
+	if current != nil {
+		currentFlags = current.flags
+	}
+	if rejection := c.updateGuard.check(currentFlags, newFlags); rejection != nil {
+		return c.reject(hash, newUpdateRefusedError(rejection.Reason), *rejection)
+	}
+
+	newSnapshot := NewSnapshot(newFlags, newSegments)
+	newSnapshot.hash = hash
+	c.rejectedMutex.Lock()
+	c.rejectedErr = nil
+	c.rejectedMutex.Unlock()
+
+	oldCacheFlags := map[string]flag.Flag{}
+	var oldSegments flag.Segments
+	if oldSnapshot := c.snapshot.Swap(&newSnapshot).(*Snapshot); oldSnapshot != nil {
+		// the flags are compared as they were when they have been notified, so the steps of the
+		// scheduled rollouts applied since then are notified once.
+		oldCacheFlags = oldSnapshot.flagsAt(oldSnapshot.notifiedAt)
+		oldSegments = oldSnapshot.segments
 	}
-	c.inMemoryCache = newCache
-	c.latestUpdate = time.Now()
-	c.mutex.Unlock()
 
 	// notify the changes
-	c.notificationService.Notify(oldCacheFlags, newCacheFlags)
+	c.notificationService.Notify(
+		oldCacheFlags, newSnapshot.flagsAt(newSnapshot.notifiedAt), oldSegments, newSegments)
+
+	if len(warnings) > 0 {
+		return newInvalidRulesError(warnings)
+	}
 	return nil
 }
 
+// reject notifies the rejection of the flag file and returns the error,
+// the same flag file is refused again without being notified.
+func (c *cacheManagerImpl) reject(hash [sha256.Size]byte, err error, rejection ffnotifier.UpdateRejection) error {
+	c.rejectedMutex.Lock()
+	c.rejectedHash, c.rejectedErr = hash, err
+	c.rejectedMutex.Unlock()
+
+	if c.notificationService != nil {
+		c.notificationService.NotifyRejection(rejection)
+	}
+	return err
+}
+
+// previousRejection returns the error of the latest flag file refused if it has the same hash.
+func (c *cacheManagerImpl) previousRejection(hash [sha256.Size]byte) error {
+	c.rejectedMutex.Lock()
+	defer c.rejectedMutex.Unlock()
+	if c.rejectedErr != nil && c.rejectedHash == hash {
+		return c.rejectedErr
+	}
+	return nil
+}
+
+// notifyScheduledSteps notifies the steps of the scheduled rollouts reached since the flags
+// of this snapshot have been notified, the flags are not rebuilt.
+func (c *cacheManagerImpl) notifyScheduledSteps(s *Snapshot) {
+	if len(s.scheduledFlags) == 0 {
+		return
+	}
+	newSnapshot := *s
+	newSnapshot.notifiedAt = time.Now()
+	if !c.snapshot.CompareAndSwap(s, &newSnapshot) {
+		// the cache has been updated in the meantime, the changes have been notified.
+		return
+	}
+	c.notificationService.Notify(
+		s.scheduledFlagsAt(s.notifiedAt), newSnapshot.scheduledFlagsAt(newSnapshot.notifiedAt), nil, nil)
+}
+
 func (c *cacheManagerImpl) Close() {
 	// Clear the cache
-	c.mutex.Lock()
-	c.inMemoryCache = nil
-	c.mutex.Unlock()
+	c.snapshot.Store((*Snapshot)(nil))
 	if c.notificationService != nil {
 		c.notificationService.Close()
 	}
 }
 
+// currentSnapshot returns the latest Snapshot, it is nil if the cache is closed.
+func (c *cacheManagerImpl) currentSnapshot() *Snapshot {
+	return c.snapshot.Load().(*Snapshot)
+}
+
 func (c *cacheManagerImpl) GetFlag(key string) (flag.Flag, error) {
-	c.mutex.RLock()
-	defer c.mutex.RUnlock()
-	if c.inMemoryCache == nil {
-		return nil, errors.New("impossible to read the flag before the initialisation")
+	s := c.currentSnapshot()
+	if s == nil {
+		return nil, newNotInitializedError("flag")
 	}
-	return c.inMemoryCache.getFlag(key)
+	return s.GetFlag(key)
 }
 
+// AllFlags returns the flags of the cache, the map is shared and must not be modified.
 func (c *cacheManagerImpl) AllFlags() (map[string]flag.Flag, error) {
-	c.mutex.RLock()
-	defer c.mutex.RUnlock()
-	if c.inMemoryCache == nil {
-		return nil, errors.New("impossible to read the flag before the initialisation")
+	s := c.currentSnapshot()
+	if s == nil {
+		return nil, newNotInitializedError("flag")
 	}
-	return c.inMemoryCache.All(), nil
+	return s.AllFlags(), nil
+}
+
+func (c *cacheManagerImpl) GetSegments() (flag.Segments, error) {
+	s := c.currentSnapshot()
+	if s == nil {
+		return nil, newNotInitializedError("segments")
+	}
+	return s.segments, nil
 }
 
 func (c *cacheManagerImpl) GetLatestUpdateDate() time.Time {
-	c.mutex.RLock()
-	defer c.mutex.RUnlock()
-	return c.latestUpdate
+	s := c.currentSnapshot()
+	if s == nil {
+		return time.Time{}
+	}
+	return s.latestUpdate
+}
+
+// GetLatestCheckDate returns the date of the latest update of the cache,
+// even if the flags did not change.
+func (c *cacheManagerImpl) GetLatestCheckDate() time.Time {
+	latestCheck := atomic.LoadInt64(&c.latestCheck)
+	if latestCheck == 0 || c.currentSnapshot() == nil {
+		return time.Time{}
+	}
+	return time.Unix(0, latestCheck)
+}
+
+// Snapshot returns the flags and segments currently in the cache.
+// The Snapshot is replaced at every update, so it is never modified by an update.
+func (c *cacheManagerImpl) Snapshot() (Snapshot, error) {
+	s := c.currentSnapshot()
+	if s == nil {
+		return Snapshot{}, newNotInitializedError("flag")
+	}
+	return *s, nil
 }
@@ -1,12 +1,16 @@
 package cache_test
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/thomaspoignant/go-feature-flag/ffnotifier"
 	"github.com/thomaspoignant/go-feature-flag/internal/cache"
+	"github.com/thomaspoignant/go-feature-flag/internal/flag"
 	flagv1 "github.com/thomaspoignant/go-feature-flag/internal/flagv1"
+	"github.com/thomaspoignant/go-feature-flag/internal/flagv2"
 	"github.com/thomaspoignant/go-feature-flag/testutils/testconvert"
 )
 
@@ -15,12 +19,14 @@ func Test_FlagCacheNotInit(t *testing.T) {
 	fCache.Close()
 	_, err := fCache.GetFlag("test-flag")
 	assert.Error(t, err, "We should have an error if the cache is not init")
+	assert.ErrorIs(t, err, cache.ErrNotInitialized)
 }
 
 func Test_GetFlagNotExist(t *testing.T) {
 	fCache := cache.New(nil)
 	_, err := fCache.GetFlag("not-exists-flag")
 	assert.Error(t, err, "We should have an error if the flag does not exists")
+	assert.ErrorIs(t, err, cache.ErrFlagNotFound)
 }
 
 func Test_FlagCache(t *testing.T) {
@@ -174,6 +180,8 @@ disable = false`),
 			// If no error we compare with expected
 			for key, expected := range tt.expected {
 				got, _ := fCache.GetFlag(key)
+				// the queries of the expected flag are parsed like the ones of the loaded flags.
+				assert.NoError(t, expected.Init())
 				assert.Equal(t, &expected, got) // nolint
 			}
 			fCache.Close()
@@ -288,6 +296,7 @@ test-flag2:
 			// If no error we compare with expected
 			for key, expected := range tt.expected {
 				got := allFlags[key]
+				assert.NoError(t, expected.Init())
 				assert.Equal(t, &expected, got) //nolint: gosec
 			}
 			fCache.Close()
@@ -312,3 +321,762 @@ func Test_cacheManagerImpl_GetLatestUpdateDate(t *testing.T) {
 
 	assert.True(t, timeBefore.Before(timeAfter))
 }
+
+func Test_FlagCacheMultiVariation(t *testing.T) {
+	yamlFile := []byte(`test-flag:
+  rule: key eq "random-key"
+  percentage: 100
+  true: true
+  false: false
+  default: false
+test-flag-v2:
+  variations:
+    red: "red"
+    blue: "blue"
+  targeting:
+    - name: rule1
+      query: key eq "random-key"
+      variation: blue
+  defaultRule:
+    variation: red
+`)
+
+	jsonFile := []byte(`{
+  "test-flag": {
+    "rule": "key eq \"random-key\"",
+    "percentage": 100,
+    "true": true,
+    "false": false,
+    "default": false
+  },
+  "test-flag-v2": {
+    "variations": {
+      "red": "red",
+      "blue": "blue"
+    },
+    "targeting": [
+      {
+        "name": "rule1",
+        "query": "key eq \"random-key\"",
+        "variation": "blue"
+      }
+    ],
+    "defaultRule": {
+      "variation": "red"
+    }
+  }
+}
+`)
+
+	tomlFile := []byte(`[test-flag]
+rule = "key eq \"random-key\""
+percentage = 100.0
+true = true
+false = false
+default = false
+
+[test-flag-v2]
+  [test-flag-v2.variations]
+  red = "red"
+  blue = "blue"
+
+  [[test-flag-v2.targeting]]
+  name = "rule1"
+  query = "key eq \"random-key\""
+  variation = "blue"
+
+  [test-flag-v2.defaultRule]
+  variation = "red"
+`)
+
+	expected := map[string]flag.Flag{
+		"test-flag": &flagv1.FlagData{
+			Rule:       testconvert.String("key eq \"random-key\""),
+			Percentage: testconvert.Float64(100),
+			True:       testconvert.Interface(true),
+			False:      testconvert.Interface(false),
+			Default:    testconvert.Interface(false),
+		},
+		"test-flag-v2": &flagv2.FlagData{
+			Variations: map[string]*interface{}{
+				"red":  testconvert.Interface("red"),
+				"blue": testconvert.Interface("blue"),
+			},
+			Targeting: []flagv2.Rule{
+				{
+					Name:      testconvert.String("rule1"),
+					Query:     testconvert.String("key eq \"random-key\""),
+					Variation: testconvert.String("blue"),
+				},
+			},
+			DefaultRule: &flagv2.Rule{Variation: testconvert.String("red")},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		loadedFlags []byte
+		flagFormat  string
+		expected    map[string]flag.Flag
+		wantErr     bool
+	}{
+		{
+			name:        "Yaml valid",
+			loadedFlags: yamlFile,
+			flagFormat:  "yaml",
+			expected:    expected,
+		},
+		{
+			name:        "JSON valid",
+			loadedFlags: jsonFile,
+			flagFormat:  "json",
+			expected:    expected,
+		},
+		{
+			name:        "TOML valid",
+			loadedFlags: tomlFile,
+			flagFormat:  "toml",
+			expected:    expected,
+		},
+		{
+			name: "Unknown variation in a rule",
+			loadedFlags: []byte(`test-flag-v2:
+  variations:
+    red: "red"
+  targeting:
+    - query: key eq "random-key"
+      variation: blue
+  defaultRule:
+    variation: red
+`),
+			flagFormat: "yaml",
+			wantErr:    true,
+		},
+		{
+			name: "Missing default rule",
+			loadedFlags: []byte(`test-flag-v2:
+  variations:
+    red: "red"
+`),
+			flagFormat: "yaml",
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fCache := cache.New(cache.NewNotificationService([]ffnotifier.Notifier{}))
+			defer fCache.Close()
+			err := fCache.UpdateCache(tt.loadedFlags, tt.flagFormat)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			allFlags, err := fCache.AllFlags()
+			assert.NoError(t, err)
+			for _, expected := range tt.expected {
+				assert.NoError(t, expected.(interface{ Init() error }).Init())
+			}
+			assert.Equal(t, tt.expected, allFlags)
+		})
+	}
+}
+
+func Test_FlagCachePrerequisites(t *testing.T) {
+	tests := []struct {
+		name        string
+		loadedFlags []byte
+		wantErr     string
+	}{
+		{
+			name: "Valid prerequisites",
+			loadedFlags: []byte(`flag-a:
+  percentage: 100
+  true: true
+  false: false
+  default: false
+flag-b:
+  prerequisites:
+    - key: flag-a
+      variation: "True"
+  percentage: 100
+  true: true
+  false: false
+  default: false
+flag-c:
+  prerequisites:
+    - key: flag-a
+      variation: "True"
+    - key: flag-b
+      variation: "True"
+    - key: unknown-flag
+      variation: "True"
+  percentage: 100
+  true: true
+  false: false
+  default: false
+`),
+		},
+		{
+			name: "Flag depending on itself",
+			loadedFlags: []byte(`flag-a:
+  prerequisites:
+    - key: flag-a
+      variation: "True"
+  percentage: 100
+  true: true
+  false: false
+  default: false
+`),
+			wantErr: "circular dependency in the flag prerequisites: flag-a -> flag-a",
+		},
+		{
+			name: "Circular dependency",
+			loadedFlags: []byte(`flag-a:
+  prerequisites:
+    - key: flag-b
+      variation: "True"
+  percentage: 100
+  true: true
+  false: false
+  default: false
+flag-b:
+  prerequisites:
+    - key: flag-c
+      variation: "blue"
+  percentage: 100
+  true: true
+  false: false
+  default: false
+flag-c:
+  prerequisites:
+    - key: flag-a
+      variation: "True"
+  variations:
+    red: "red"
+    blue: "blue"
+  defaultRule:
+    variation: red
+`),
+			wantErr: "circular dependency in the flag prerequisites: flag-a -> flag-b -> flag-c -> flag-a",
+		},
+		{
+			name: "cycle added by a scheduled step",
+			loadedFlags: []byte(`flag-a:
+  prerequisites:
+    - key: flag-b
+      variation: "True"
+  percentage: 100
+  true: true
+  false: false
+  default: false
+flag-b:
+  percentage: 100
+  true: true
+  false: false
+  default: false
+  rollout:
+    scheduled:
+      steps:
+        - date: 2020-04-10T15:04:05.00+02:00
+          prerequisites:
+            - key: flag-a
+              variation: "True"
+`),
+			wantErr: "circular dependency in the flag prerequisites: flag-a -> flag-b -> flag-a",
+		},
+		{
+			name: "cycle added by a future scheduled step",
+			loadedFlags: []byte(`flag-a:
+  prerequisites:
+    - key: flag-b
+      variation: "True"
+  percentage: 100
+  true: true
+  false: false
+  default: false
+flag-b:
+  percentage: 100
+  true: true
+  false: false
+  default: false
+  rollout:
+    scheduled:
+      steps:
+        - date: 2999-04-10T15:04:05.00+02:00
+          prerequisites:
+            - key: flag-a
+              variation: "True"
+`),
+			wantErr: "circular dependency in the flag prerequisites: flag-a -> flag-b -> flag-a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fCache := cache.New(cache.NewNotificationService([]ffnotifier.Notifier{}))
+			defer fCache.Close()
+			err := fCache.UpdateCache(tt.loadedFlags, "yaml")
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func Test_FlagCacheSegments(t *testing.T) {
+	tests := []struct {
+		name         string
+		loadedFlags  []byte
+		flagFormat   string
+		wantSegments flag.Segments
+		wantErr      string
+	}{
+		{
+			name:       "Yaml",
+			flagFormat: "yaml",
+			loadedFlags: []byte(`segments:
+  beta-testers:
+    query: beta eq true
+  internal-users:
+    keys:
+      - user-1
+      - user-2
+test-flag:
+  rule: segment "beta-testers"
+  percentage: 100
+  true: true
+  false: false
+  default: false
+`),
+			wantSegments: flag.Segments{
+				"beta-testers":   {Query: testconvert.String("beta eq true")},
+				"internal-users": {Keys: []string{"user-1", "user-2"}},
+			},
+		},
+		{
+			name:       "JSON",
+			flagFormat: "json",
+			loadedFlags: []byte(`{
+  "segments": {
+    "beta-testers": {
+      "query": "beta eq true",
+      "keys": ["user-1"]
+    }
+  },
+  "test-flag": {
+    "rule": "segment \"beta-testers\"",
+    "percentage": 100,
+    "true": true,
+    "false": false,
+    "default": false
+  }
+}`),
+			wantSegments: flag.Segments{
+				"beta-testers": {Query: testconvert.String("beta eq true"), Keys: []string{"user-1"}},
+			},
+		},
+		{
+			name:       "TOML",
+			flagFormat: "toml",
+			loadedFlags: []byte(`[segments.beta-testers]
+query = "beta eq true"
+
+[test-flag]
+rule = "segment \"beta-testers\""
+percentage = 100.0
+true = true
+false = false
+default = false
+`),
+			wantSegments: flag.Segments{
+				"beta-testers": {Query: testconvert.String("beta eq true")},
+			},
+		},
+		{
+			name:       "No segments",
+			flagFormat: "yaml",
+			loadedFlags: []byte(`test-flag:
+  percentage: 100
+  true: true
+  false: false
+  default: false
+`),
+			wantSegments: flag.Segments{},
+		},
+		{
+			name:       "Empty segment",
+			flagFormat: "yaml",
+			loadedFlags: []byte(`segments:
+  beta-testers: {}
+`),
+			wantErr: "invalid segment beta-testers: a segment should have a query or a list of keys",
+		},
+		{
+			name:       "Segment referencing another segment",
+			flagFormat: "yaml",
+			loadedFlags: []byte(`segments:
+  beta-testers:
+    query: segment "internal-users"
+  internal-users:
+    keys:
+      - user-1
+`),
+			wantErr: "invalid segment beta-testers: a segment cannot reference another segment",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fCache := cache.New(cache.NewNotificationService([]ffnotifier.Notifier{}))
+			defer fCache.Close()
+			err := fCache.UpdateCache(tt.loadedFlags, tt.flagFormat)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+
+			segments, err := fCache.GetSegments()
+			assert.NoError(t, err)
+			for name, segment := range tt.wantSegments {
+				assert.NoError(t, segment.Init())
+				tt.wantSegments[name] = segment
+			}
+			assert.Equal(t, tt.wantSegments, segments)
+
+			flags, err := fCache.AllFlags()
+			assert.NoError(t, err)
+			_, segmentIsAFlag := flags["segments"]
+			assert.False(t, segmentIsAFlag)
+		})
+	}
+}
+
+func Test_GetSegmentsNotInit(t *testing.T) {
+	fCache := cache.New(cache.NewNotificationService([]ffnotifier.Notifier{}))
+	fCache.Close()
+	_, err := fCache.GetSegments()
+	assert.ErrorIs(t, err, cache.ErrNotInitialized)
+}
+
+func Test_FlagCacheTargets(t *testing.T) {
+	tests := []struct {
+		name        string
+		loadedFlags []byte
+		wantErr     string
+	}{
+		{
+			name: "Valid targets",
+			loadedFlags: []byte(`flag-v1:
+  targets:
+    "True":
+      - user-1
+  percentage: 0
+  true: true
+  false: false
+  default: false
+flag-v2:
+  targets:
+    red:
+      - user-1
+  variations:
+    red: "red"
+    blue: "blue"
+  defaultRule:
+    variation: blue
+`),
+		},
+		{
+			name: "Unknown variation",
+			loadedFlags: []byte(`flag-v1:
+  targets:
+    "Red":
+      - user-1
+  percentage: 0
+  true: true
+  false: false
+  default: false
+`),
+			wantErr: "invalid flag flag-v1: unknown variation Red in the targets",
+		},
+		{
+			name: "User targeted twice",
+			loadedFlags: []byte(`flag-v2:
+  targets:
+    red:
+      - user-1
+    blue:
+      - user-1
+  variations:
+    red: "red"
+    blue: "blue"
+  defaultRule:
+    variation: blue
+`),
+			wantErr: "invalid flag flag-v2: user user-1 is targeted by the variations blue and red",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fCache := cache.New(cache.NewNotificationService([]ffnotifier.Notifier{}))
+			defer fCache.Close()
+			err := fCache.UpdateCache(tt.loadedFlags, "yaml")
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func Test_FlagCacheInvalidRule(t *testing.T) {
+	tests := []struct {
+		name         string
+		loadedFlags  []byte
+		wantErr      string
+		invalidRules bool
+	}{
+		{
+			name: "Invalid rule",
+			loadedFlags: []byte(`test-flag:
+  rule: key eq "random-key" and
+  percentage: 100
+  true: true
+  false: false
+  default: false
+`),
+			wantErr: "flag file loaded with invalid rules that never match: " +
+				"flag test-flag: invalid rule: unexpected end of the query, expecting an attribute name",
+			invalidRules: true,
+		},
+		{
+			name: "Invalid targeting rule",
+			loadedFlags: []byte(`test-flag:
+  targeting:
+    - query: key xx "random-key"
+      percentage: 100
+  true: true
+  false: false
+  default: false
+`),
+			wantErr: "flag file loaded with invalid rules that never match: " +
+				"flag test-flag: invalid targeting rule 0: unexpected \"xx\" at position 4, expecting an operator",
+			invalidRules: true,
+		},
+		{
+			name: "Invalid rule in a scheduled step",
+			loadedFlags: []byte(`test-flag:
+  true: true
+  false: false
+  default: false
+  rollout:
+    scheduled:
+      steps:
+        - date: 2020-04-10T15:04:05.00+02:00
+          rule: beta eq
+`),
+			wantErr: "flag file loaded with invalid rules that never match: " +
+				"flag test-flag: invalid scheduled step 0: invalid rule: unexpected end of the query, expecting a value",
+			invalidRules: true,
+		},
+		{
+			name: "Unknown variation in the targets of a future scheduled step",
+			loadedFlags: []byte(`test-flag:
+  true: true
+  false: false
+  default: false
+  rollout:
+    scheduled:
+      steps:
+        - date: 2020-04-10T15:04:05.00+02:00
+          percentage: 50
+        - date: 2999-04-10T15:04:05.00+02:00
+          targets:
+            Treu:
+              - beta-key
+`),
+			wantErr: "invalid flag test-flag: invalid scheduled step 1: unknown variation Treu in the targets",
+		},
+		{
+			name: "User targeted twice in a scheduled step",
+			loadedFlags: []byte(`test-flag:
+  true: true
+  false: false
+  default: false
+  rollout:
+    scheduled:
+      steps:
+        - date: 2999-04-10T15:04:05.00+02:00
+          targets:
+            "True":
+              - beta-key
+            "False":
+              - beta-key
+`),
+			wantErr: "invalid flag test-flag: invalid scheduled step 0: user beta-key is targeted by the variations False and True",
+		},
+		{
+			name: "Invalid rule in a multi-variation flag",
+			loadedFlags: []byte(`test-flag:
+  variations:
+    red: "red"
+    blue: "blue"
+  targeting:
+    - query: (beta eq true
+      variation: red
+  defaultRule:
+    variation: blue
+`),
+			wantErr: "flag file loaded with invalid rules that never match: " +
+				"flag test-flag: invalid rule 0: unexpected end of the query, expecting \")\"",
+			invalidRules: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fCache := cache.New(cache.NewNotificationService([]ffnotifier.Notifier{}))
+			defer fCache.Close()
+			err := fCache.UpdateCache(tt.loadedFlags, "yaml")
+			assert.EqualError(t, err, tt.wantErr)
+
+			// a rule that cannot be parsed never matches, the flag is loaded anyway.
+			_, errFlag := fCache.GetFlag("test-flag")
+			if tt.invalidRules {
+				assert.ErrorIs(t, err, cache.ErrInvalidRules)
+				assert.NoError(t, errFlag)
+			} else {
+				assert.ErrorIs(t, errFlag, cache.ErrFlagNotFound)
+			}
+		})
+	}
+}
+
+func Test_cacheManagerImpl_Snapshot(t *testing.T) {
+	fCache := cache.New(cache.NewNotificationService([]ffnotifier.Notifier{}))
+	_ = fCache.UpdateCache([]byte(`test-flag:
+  variations:
+    A: A
+    B: B
+  defaultRule:
+    variation: A
+`), "yaml")
+
+	snapshot, err := fCache.Snapshot()
+	assert.NoError(t, err)
+
+	// the snapshot is not changed by an update of the cache.
+	_ = fCache.UpdateCache([]byte(`other-flag:
+  variations:
+    A: A
+  defaultRule:
+    variation: A
+`), "yaml")
+	f, err := snapshot.GetFlag("test-flag")
+	assert.NoError(t, err)
+	assert.Equal(t, "A", f.GetDefaultVariation())
+	_, err = snapshot.GetFlag("other-flag")
+	assert.ErrorIs(t, err, cache.ErrFlagNotFound)
+
+	fCache.Close()
+	_, err = fCache.Snapshot()
+	assert.ErrorIs(t, err, cache.ErrNotInitialized)
+}
+
+func Test_cacheManagerImpl_ScheduledStepNotified(t *testing.T) {
+	notificationService := cache.NewNotificationService([]ffnotifier.Notifier{})
+	fCache := cache.New(notificationService)
+	defer fCache.Close()
+	changes := make(chan [2]flag.Flag, 10)
+	notificationService.Subscribe("scheduled-flag", func(before, after flag.Flag) {
+		changes <- [2]flag.Flag{before, after}
+	})
+
+	loadedFlags := []byte(fmt.Sprintf(`scheduled-flag:
+  true: "new"
+  false: "old"
+  default: "old"
+  percentage: 0
+  rollout:
+    scheduled:
+      steps:
+        - date: %s
+          percentage: 100
+`, time.Now().Add(500*time.Millisecond).Format(time.RFC3339Nano)))
+
+	_ = fCache.UpdateCache(loadedFlags, "yaml")
+	added := <-changes
+	assert.Nil(t, added[0])
+
+	// the file does not change, the step is notified once when its date is reached.
+	_ = fCache.UpdateCache(loadedFlags, "yaml")
+	time.Sleep(600 * time.Millisecond)
+	_ = fCache.UpdateCache(loadedFlags, "yaml")
+	_ = fCache.UpdateCache(loadedFlags, "yaml")
+
+	select {
+	case updated := <-changes:
+		assert.Equal(t, "0.00", updated[0].GetRawValues()["Percentage"])
+		assert.Equal(t, "100.00", updated[1].GetRawValues()["Percentage"])
+	case <-time.After(time.Second):
+		assert.Fail(t, "the scheduled step has not been notified")
+	}
+	time.Sleep(100 * time.Millisecond)
+	assert.Empty(t, changes)
+}
+
+func Test_cacheManagerImpl_UnchangedContent(t *testing.T) {
+	notificationService := cache.NewNotificationService([]ffnotifier.Notifier{})
+	fCache := cache.New(notificationService)
+	defer fCache.Close()
+	changes := make(chan [2]flag.Flag, 10)
+	notificationService.Subscribe("test-flag", func(before, after flag.Flag) {
+		changes <- [2]flag.Flag{before, after}
+	})
+	assert.True(t, fCache.GetLatestCheckDate().IsZero())
+
+	loadedFlags := []byte(`test-flag:
+  percentage: 100
+  true: true
+  false: false
+  default: false
+`)
+	assert.NoError(t, fCache.UpdateCache(loadedFlags, "yaml"))
+	<-changes
+	firstUpdate := fCache.GetLatestUpdateDate()
+	firstCheck := fCache.GetLatestCheckDate()
+	assert.False(t, firstCheck.IsZero())
+	flagBefore, _ := fCache.GetFlag("test-flag")
+
+	// the same content does not rebuild the cache, only the check date is refreshed.
+	time.Sleep(10 * time.Millisecond)
+	assert.NoError(t, fCache.UpdateCache(append([]byte{}, loadedFlags...), "yaml"))
+	assert.Equal(t, firstUpdate, fCache.GetLatestUpdateDate())
+	assert.True(t, fCache.GetLatestCheckDate().After(firstCheck))
+	flagAfter, _ := fCache.GetFlag("test-flag")
+	assert.Same(t, flagBefore, flagAfter)
+
+	// a new content rebuilds the cache.
+	assert.NoError(t, fCache.UpdateCache([]byte(`test-flag:
+  percentage: 0
+  true: true
+  false: false
+  default: false
+`), "yaml"))
+	assert.True(t, fCache.GetLatestUpdateDate().After(firstUpdate))
+	select {
+	case updated := <-changes:
+		assert.Equal(t, "0.00", updated[1].GetRawValues()["Percentage"])
+	case <-time.After(time.Second):
+		assert.Fail(t, "the change has not been notified")
+	}
+
+	fCache.Close()
+	assert.True(t, fCache.GetLatestCheckDate().IsZero())
+}
@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrFlagNotFound is matched by the error returned when the flag is not in the cache.
+	ErrFlagNotFound = errors.New("flag not found")
+
+	// ErrNotInitialized is matched by the error returned when the cache is read before its initialisation.
+	ErrNotInitialized = errors.New("cache not initialized")
+
+	// ErrUpdateRefused is matched by the error returned when the UpdateGuard refuses an update of the flag file.
+	ErrUpdateRefused = errors.New("update of the flag file refused")
+
+	// ErrInvalidRules is matched by the error returned when the flag file has been loaded, but some of its rules
+	// cannot be parsed, these rules never match.
+	ErrInvalidRules = errors.New("invalid rules in the flag file")
+)
+
+// cacheError is an error with a descriptive message that matches one of the sentinel errors with errors.Is.
+type cacheError struct {
+	message  string
+	sentinel error
+}
+
+func (e *cacheError) Error() string {
+	return e.message
+}
+
+func (e *cacheError) Is(target error) bool {
+	return target == e.sentinel
+}
+
+func newFlagNotFoundError(key string) error {
+	return &cacheError{message: fmt.Sprintf("flag [%v] does not exists", key), sentinel: ErrFlagNotFound}
+}
+
+func newNotInitializedError(object string) error {
+	return &cacheError{
+		message:  fmt.Sprintf("impossible to read the %s before the initialisation", object),
+		sentinel: ErrNotInitialized,
+	}
+}
+
+func newUpdateRefusedError(reason string) error {
+	return &cacheError{
+		message:  fmt.Sprintf("update of the flag file refused: %s", reason),
+		sentinel: ErrUpdateRefused,
+	}
+}
+
+func newInvalidRulesError(warnings []string) error {
+	return &cacheError{
+		message:  fmt.Sprintf("flag file loaded with invalid rules that never match: %s", strings.Join(warnings, "; ")),
+		sentinel: ErrInvalidRules,
+	}
+}
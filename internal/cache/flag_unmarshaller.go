@@ -0,0 +1,144 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+	"github.com/thomaspoignant/go-feature-flag/internal/flag"
+	"github.com/thomaspoignant/go-feature-flag/internal/flagv1"
+	"github.com/thomaspoignant/go-feature-flag/internal/flagv2"
+	"gopkg.in/yaml.v3"
+)
+
+// segmentsKey is the reserved top level key of the flag file containing the segments.
+const segmentsKey = "segments"
+
+// flagDecoder is decoding the content of one flag in the destination struct.
+type flagDecoder func(out interface{}) error
+
+// unmarshalFlags is reading the flag file and create a flag for each key of the file.
+// Every flag in the file can use either the flagv1 format or the multi-variation format (flagv2),
+// the format is selected by checking if the flag contains a list of variations.
+// The segments key is not a flag, it contains the segments that can be used in the rules.
+// The rules that cannot be parsed never match, they are returned as warnings and do not prevent
+// the flag file to be loaded.
+func unmarshalFlags(
+	loadedFlags []byte, fileFormat string) (map[string]flag.Flag, flag.Segments, []string, error) {
+	decoders, err := splitFlags(loadedFlags, fileFormat)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var warnings []string
+	segments := flag.Segments{}
+	if decoder, ok := decoders[segmentsKey]; ok {
+		delete(decoders, segmentsKey)
+		if err := decoder(&segments); err != nil {
+			return nil, nil, nil, err
+		}
+		for name, segment := range segments {
+			if err := segment.Validate(); err != nil {
+				return nil, nil, nil, fmt.Errorf("invalid segment %s: %v", name, err)
+			}
+			if warnings, err = initWarnings(segment.Init(), "segment "+name, warnings); err != nil {
+				return nil, nil, nil, err
+			}
+			segments[name] = segment
+		}
+	}
+
+	flags := make(map[string]flag.Flag, len(decoders))
+	for key, decoder := range decoders {
+		var probe struct {
+			Variations interface{} `json:"variations" yaml:"variations" toml:"variations"`
+		}
+		if err := decoder(&probe); err != nil {
+			return nil, nil, nil, err
+		}
+
+		if probe.Variations == nil {
+			var f flagv1.FlagData
+			if err := decoder(&f); err != nil {
+				return nil, nil, nil, err
+			}
+			if err := f.Validate(); err != nil {
+				return nil, nil, nil, fmt.Errorf("invalid flag %s: %v", key, err)
+			}
+			if warnings, err = initWarnings(f.Init(), "flag "+key, warnings); err != nil {
+				return nil, nil, nil, err
+			}
+			flags[key] = &f
+			continue
+		}
+
+		var f flagv2.FlagData
+		if err := decoder(&f); err != nil {
+			return nil, nil, nil, err
+		}
+		if err := f.Validate(); err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid flag %s: %v", key, err)
+		}
+		if warnings, err = initWarnings(f.Init(), "flag "+key, warnings); err != nil {
+			return nil, nil, nil, err
+		}
+		flags[key] = &f
+	}
+	sort.Strings(warnings)
+	return flags, segments, warnings, nil
+}
+
+// initWarnings adds the invalid rules returned by Init to the warnings,
+// any other error of Init is returned because the flag or the segment cannot be used.
+func initWarnings(err error, name string, warnings []string) ([]string, error) {
+	var invalidRules flag.InvalidRulesError
+	if err == nil {
+		return warnings, nil
+	}
+	if !errors.As(err, &invalidRules) {
+		return nil, fmt.Errorf("invalid %s: %v", name, err)
+	}
+	return append(warnings, fmt.Sprintf("%s: %v", name, invalidRules)), nil
+}
+
+// splitFlags is parsing the file and returns a decoder for each flag of the file.
+func splitFlags(loadedFlags []byte, fileFormat string) (map[string]flagDecoder, error) {
+	decoders := map[string]flagDecoder{}
+	switch strings.ToLower(fileFormat) {
+	case "toml":
+		tree, err := toml.LoadBytes(loadedFlags)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range tree.Keys() {
+			subTree, ok := tree.GetPath([]string{key}).(*toml.Tree)
+			if !ok {
+				return nil, fmt.Errorf("flag %s is not a valid table", key)
+			}
+			decoders[key] = subTree.Unmarshal
+		}
+	case "json":
+		var rawFlags map[string]json.RawMessage
+		if err := json.Unmarshal(loadedFlags, &rawFlags); err != nil {
+			return nil, err
+		}
+		for key, rawFlag := range rawFlags {
+			rawFlag := rawFlag
+			decoders[key] = func(out interface{}) error { return json.Unmarshal(rawFlag, out) }
+		}
+	default:
+		// default unmarshaller is YAML
+		var rawFlags map[string]yaml.Node
+		if err := yaml.Unmarshal(loadedFlags, &rawFlags); err != nil {
+			return nil, err
+		}
+		for key, rawFlag := range rawFlags {
+			rawFlag := rawFlag
+			decoders[key] = rawFlag.Decode
+		}
+	}
+	return decoders, nil
+}
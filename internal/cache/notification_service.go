@@ -4,30 +4,54 @@ import (
 	"sync"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/thomaspoignant/go-feature-flag/ffnotifier"
 	"github.com/thomaspoignant/go-feature-flag/internal/flag"
+	"github.com/thomaspoignant/go-feature-flag/internal/flagv1"
+	"github.com/thomaspoignant/go-feature-flag/internal/flagv2"
 )
 
+// ignoreComputedFields ignores the fields computed when loading the flags, they are
+// derived from the configuration and are not a change of the flag.
+var ignoreComputedFields = cmpopts.IgnoreUnexported(
+	flagv1.FlagData{}, flagv1.Rule{}, flagv2.FlagData{}, flagv2.Rule{}, flag.Segment{})
+
 type Service interface {
 	Close()
-	Notify(oldCache map[string]flag.Flag, newCache map[string]flag.Flag)
+	Notify(oldCache map[string]flag.Flag, newCache map[string]flag.Flag, oldSegments flag.Segments, newSegments flag.Segments)
+
+	// NotifyRejection informs the notifiers implementing ffnotifier.RejectionNotifier that
+	// an update of the flag file has been refused.
+	NotifyRejection(rejection ffnotifier.UpdateRejection)
+
+	// Subscribe registers a listener called every time the flag is added (before is nil),
+	// updated or deleted (after is nil).
+	Subscribe(flagKey string, listener func(before, after flag.Flag)) *Subscription
 }
 
 func NewNotificationService(notifiers []ffnotifier.Notifier) Service {
 	return &notificationService{
-		Notifiers: notifiers,
-		waitGroup: &sync.WaitGroup{},
+		Notifiers:     notifiers,
+		waitGroup:     &sync.WaitGroup{},
+		subscriptions: map[string]map[*Subscription]func(before, after flag.Flag){},
 	}
 }
 
 type notificationService struct {
 	Notifiers []ffnotifier.Notifier
 	waitGroup *sync.WaitGroup
+
+	subscriptionsMutex sync.RWMutex
+	subscriptions      map[string]map[*Subscription]func(before, after flag.Flag)
 }
 
-func (c *notificationService) Notify(oldCache map[string]flag.Flag, newCache map[string]flag.Flag) {
+func (c *notificationService) Notify(
+	oldCache map[string]flag.Flag, newCache map[string]flag.Flag, oldSegments flag.Segments, newSegments flag.Segments,
+) {
 	diff := c.getDifferences(oldCache, newCache)
+	c.addSegmentDifferences(&diff, oldSegments, newSegments)
 	if diff.HasDiff() {
+		c.notifySubscriptions(diff)
 		for _, notifier := range c.Notifiers {
 			c.waitGroup.Add(1)
 			go notifier.Notify(diff, c.waitGroup)
@@ -35,10 +59,80 @@ func (c *notificationService) Notify(oldCache map[string]flag.Flag, newCache map
 	}
 }
 
+func (c *notificationService) NotifyRejection(rejection ffnotifier.UpdateRejection) {
+	for _, notifier := range c.Notifiers {
+		if rejectionNotifier, ok := notifier.(ffnotifier.RejectionNotifier); ok {
+			c.waitGroup.Add(1)
+			go rejectionNotifier.NotifyRejection(rejection, c.waitGroup)
+		}
+	}
+}
+
 func (c *notificationService) Close() {
+	c.subscriptionsMutex.RLock()
+	subscriptions := make([]*Subscription, 0)
+	for _, flagSubscriptions := range c.subscriptions {
+		for s := range flagSubscriptions {
+			subscriptions = append(subscriptions, s)
+		}
+	}
+	c.subscriptionsMutex.RUnlock()
+	for _, s := range subscriptions {
+		s.Unsubscribe()
+	}
+
 	c.waitGroup.Wait()
 }
 
+func (c *notificationService) Subscribe(flagKey string, listener func(before, after flag.Flag)) *Subscription {
+	s := NewSubscription(func(s *Subscription) {
+		c.subscriptionsMutex.Lock()
+		defer c.subscriptionsMutex.Unlock()
+		delete(c.subscriptions[flagKey], s)
+		if len(c.subscriptions[flagKey]) == 0 {
+			delete(c.subscriptions, flagKey)
+		}
+	})
+
+	c.subscriptionsMutex.Lock()
+	defer c.subscriptionsMutex.Unlock()
+	if c.subscriptions == nil {
+		c.subscriptions = map[string]map[*Subscription]func(before, after flag.Flag){}
+	}
+	if c.subscriptions[flagKey] == nil {
+		c.subscriptions[flagKey] = map[*Subscription]func(before, after flag.Flag){}
+	}
+	c.subscriptions[flagKey][s] = listener
+	return s
+}
+
+// notifySubscriptions queues the changes of the flags for their subscriptions.
+func (c *notificationService) notifySubscriptions(diff ffnotifier.DiffCache) {
+	c.subscriptionsMutex.RLock()
+	defer c.subscriptionsMutex.RUnlock()
+	for flagKey, flagSubscriptions := range c.subscriptions {
+		added, isAdded := diff.Added[flagKey]
+		deleted, isDeleted := diff.Deleted[flagKey]
+		updated, isUpdated := diff.Updated[flagKey]
+		var before, after flag.Flag
+		switch {
+		case isAdded:
+			after = added
+		case isDeleted:
+			before = deleted
+		case isUpdated:
+			before, after = updated.Before, updated.After
+		default:
+			continue
+		}
+
+		for s, listener := range flagSubscriptions {
+			listener := listener
+			s.Push(func() { listener(before, after) })
+		}
+	}
+}
+
 // getDifferences is checking what are the difference in the updated cache.
 func (c *notificationService) getDifferences(
 	oldCache map[string]flag.Flag, newCache map[string]flag.Flag,
@@ -56,7 +150,7 @@ func (c *notificationService) getDifferences(
 			continue
 		}
 
-		if !cmp.Equal(oldCache[key], newCache[key]) {
+		if !cmp.Equal(oldCache[key], newCache[key], ignoreComputedFields) {
 			diff.Updated[key] = ffnotifier.DiffUpdated{
 				Before: oldFlag,
 				After:  newFlag,
@@ -72,3 +166,29 @@ func (c *notificationService) getDifferences(
 	}
 	return diff
 }
+
+// addSegmentDifferences is checking what are the differences in the segments and add them to the diff.
+func (c *notificationService) addSegmentDifferences(
+	diff *ffnotifier.DiffCache, oldSegments flag.Segments, newSegments flag.Segments,
+) {
+	diff.DeletedSegments = map[string]flag.Segment{}
+	diff.AddedSegments = map[string]flag.Segment{}
+	diff.UpdatedSegments = map[string]ffnotifier.DiffSegmentUpdated{}
+	for name, oldSegment := range oldSegments {
+		newSegment, inNewSegments := newSegments[name]
+		if !inNewSegments {
+			diff.DeletedSegments[name] = oldSegment
+			continue
+		}
+
+		if !cmp.Equal(oldSegment, newSegment, ignoreComputedFields) {
+			diff.UpdatedSegments[name] = ffnotifier.DiffSegmentUpdated{Before: oldSegment, After: newSegment}
+		}
+	}
+
+	for name, newSegment := range newSegments {
+		if _, inOldSegments := oldSegments[name]; !inOldSegments {
+			diff.AddedSegments[name] = newSegment
+		}
+	}
+}
@@ -3,6 +3,7 @@ package cache
 import (
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/thomaspoignant/go-feature-flag/ffnotifier"
@@ -156,3 +157,79 @@ func Test_notificationService_getDifferences(t *testing.T) {
 		})
 	}
 }
+
+func Test_notificationService_addSegmentDifferences(t *testing.T) {
+	oldSegments := flag.Segments{
+		"deleted": {Keys: []string{"user-1"}},
+		"updated": {Query: testconvert.String("beta eq true")},
+		"same":    {Keys: []string{"user-2"}},
+	}
+	newSegments := flag.Segments{
+		"updated": {Query: testconvert.String("beta eq false")},
+		"same":    {Keys: []string{"user-2"}},
+		"added":   {Keys: []string{"user-3"}},
+	}
+
+	c := &notificationService{}
+	diff := ffnotifier.DiffCache{}
+	c.addSegmentDifferences(&diff, oldSegments, newSegments)
+	assert.Equal(t, ffnotifier.DiffCache{
+		DeletedSegments: map[string]flag.Segment{"deleted": {Keys: []string{"user-1"}}},
+		AddedSegments:   map[string]flag.Segment{"added": {Keys: []string{"user-3"}}},
+		UpdatedSegments: map[string]ffnotifier.DiffSegmentUpdated{
+			"updated": {
+				Before: flag.Segment{Query: testconvert.String("beta eq true")},
+				After:  flag.Segment{Query: testconvert.String("beta eq false")},
+			},
+		},
+	}, diff)
+	assert.True(t, diff.HasDiff())
+}
+
+func Test_notificationService_Subscribe(t *testing.T) {
+	c := NewNotificationService([]ffnotifier.Notifier{})
+	defer c.Close()
+
+	v1 := &flagv1.FlagData{Percentage: testconvert.Float64(10)}
+	v2 := &flagv1.FlagData{Percentage: testconvert.Float64(20)}
+	v3 := &flagv1.FlagData{Percentage: testconvert.Float64(30)}
+
+	changes := make(chan ffnotifier.DiffUpdated)
+	subscription := c.Subscribe("test-flag", func(before, after flag.Flag) {
+		// the channel is not read before all the notifications are sent, the listener is slow.
+		changes <- ffnotifier.DiffUpdated{Before: before, After: after}
+	})
+
+	c.Notify(map[string]flag.Flag{}, map[string]flag.Flag{"test-flag": v1}, nil, nil)
+	c.Notify(map[string]flag.Flag{"test-flag": v1}, map[string]flag.Flag{"test-flag": v2}, nil, nil)
+	c.Notify(map[string]flag.Flag{"test-flag": v2, "other-flag": v1},
+		map[string]flag.Flag{"test-flag": v2, "other-flag": v3}, nil, nil)
+	c.Notify(map[string]flag.Flag{"test-flag": v2}, map[string]flag.Flag{"test-flag": v3}, nil, nil)
+	c.Notify(map[string]flag.Flag{"test-flag": v3}, map[string]flag.Flag{}, nil, nil)
+
+	assert.Equal(t, ffnotifier.DiffUpdated{Before: nil, After: v1}, <-changes)
+	assert.Equal(t, ffnotifier.DiffUpdated{Before: v1, After: v2}, <-changes)
+	assert.Equal(t, ffnotifier.DiffUpdated{Before: v2, After: v3}, <-changes)
+	assert.Equal(t, ffnotifier.DiffUpdated{Before: v3, After: nil}, <-changes)
+
+	subscription.Unsubscribe()
+	<-subscription.Done()
+	c.Notify(map[string]flag.Flag{}, map[string]flag.Flag{"test-flag": v1}, nil, nil)
+	select {
+	case change := <-changes:
+		assert.Fail(t, "no change expected after unsubscribe", "%v", change)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func Test_notificationService_CloseStopsSubscriptions(t *testing.T) {
+	c := NewNotificationService([]ffnotifier.Notifier{})
+	subscription := c.Subscribe("test-flag", func(before, after flag.Flag) {})
+	c.Close()
+
+	select {
+	case <-subscription.Done():
+	case <-time.After(time.Second):
+		assert.Fail(t, "the subscription should be stopped when the service is closed")
+	}
+}
@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/thomaspoignant/go-feature-flag/internal/flag"
+	"github.com/thomaspoignant/go-feature-flag/internal/flagv1"
+)
+
+// checkPrerequisites is verifying that there is no circular dependency between the flags
+// through their prerequisites, a cycle would make the evaluation of the flags impossible.
+// The prerequisites of every step of the scheduled rollouts are checked, as the steps can become
+// active in any order between the flags.
+func checkPrerequisites(flags map[string]flag.Flag) error {
+	const (
+		notVisited = iota
+		visiting
+		visited
+	)
+	states := make(map[string]int, len(flags))
+
+	var visit func(key string, path []string) error
+	visit = func(key string, path []string) error {
+		switch states[key] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("circular dependency in the flag prerequisites: %s",
+				strings.Join(append(path, key), " -> "))
+		}
+
+		f, ok := flags[key]
+		if !ok {
+			// unknown prerequisites are considered as not satisfied during the evaluation.
+			return nil
+		}
+
+		states[key] = visiting
+		for _, prerequisite := range prerequisitesOf(f) {
+			if err := visit(prerequisite.Key, append(path, key)); err != nil {
+				return err
+			}
+		}
+		states[key] = visited
+		return nil
+	}
+
+	// keys are sorted to always report the same cycle.
+	keys := make([]string, 0, len(flags))
+	for key := range flags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := visit(key, []string{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prerequisitesOf returns the prerequisites of the flag, including the ones set by the steps of its scheduled rollout.
+func prerequisitesOf(f flag.Flag) []flag.Prerequisite {
+	v1, ok := f.(*flagv1.FlagData)
+	if !ok || !v1.IsScheduled() {
+		return f.GetPrerequisites()
+	}
+	prerequisites := append([]flag.Prerequisite{}, f.GetPrerequisites()...)
+	for _, stage := range v1.Stages() {
+		prerequisites = append(prerequisites, stage.GetPrerequisites()...)
+	}
+	return prerequisites
+}
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"strings"
+	"time"
+
+	"github.com/thomaspoignant/go-feature-flag/internal/flag"
+	"github.com/thomaspoignant/go-feature-flag/internal/flagv1"
+)
+
+// Snapshot is an immutable view of the flags and segments of the cache at a point in time.
+// A new Snapshot is created at every update of the cache, so it can be read without lock.
+type Snapshot struct {
+	flags        map[string]flag.Flag
+	segments     flag.Segments
+	latestUpdate time.Time
+
+	// hash identifies the content and format of the flag file loaded in the snapshot.
+	hash [sha256.Size]byte
+
+	// notifiedAt is the date at which the flags have been notified for the last time,
+	// the scheduled steps reached since then are not notified yet.
+	notifiedAt time.Time
+
+	// scheduledFlags are the flags changing over time (scheduled rollout),
+	// the stage active at the time of the read is returned.
+	scheduledFlags map[string]*flagv1.FlagData
+}
+
+// NewSnapshot creates a Snapshot containing these flags and segments,
+// the flags must not be modified once the Snapshot is created.
+func NewSnapshot(flags map[string]flag.Flag, segments flag.Segments) Snapshot {
+	if flags == nil {
+		flags = map[string]flag.Flag{}
+	}
+	now := time.Now()
+	s := Snapshot{
+		flags:          flags,
+		segments:       segments,
+		latestUpdate:   now,
+		notifiedAt:     now,
+		scheduledFlags: map[string]*flagv1.FlagData{},
+	}
+	for key, f := range flags {
+		if v1, ok := f.(*flagv1.FlagData); ok && v1.IsScheduled() {
+			s.scheduledFlags[key] = v1
+		}
+	}
+	return s
+}
+
+// GetFlag returns the flag as it is now.
+func (s Snapshot) GetFlag(key string) (flag.Flag, error) {
+	if v1, ok := s.scheduledFlags[key]; ok {
+		return v1.ActiveStage(time.Now()), nil
+	}
+	f, ok := s.flags[key]
+	if !ok {
+		return &flagv1.FlagData{}, newFlagNotFoundError(key)
+	}
+	return f, nil
+}
+
+// AllFlags returns all the flags of the snapshot as they are now, the map must not be modified.
+func (s Snapshot) AllFlags() map[string]flag.Flag {
+	return s.flagsAt(time.Now())
+}
+
+// GetSegments returns the segments of the snapshot.
+func (s Snapshot) GetSegments() (flag.Segments, error) {
+	return s.segments, nil
+}
+
+// flagsAt returns all the flags of the snapshot as they are at this date,
+// the map is shared if there is no scheduled flag.
+func (s Snapshot) flagsAt(date time.Time) map[string]flag.Flag {
+	if len(s.scheduledFlags) == 0 {
+		return s.flags
+	}
+	flags := make(map[string]flag.Flag, len(s.flags))
+	for key, f := range s.flags {
+		flags[key] = f
+	}
+	for key, v1 := range s.scheduledFlags {
+		flags[key] = v1.ActiveStage(date)
+	}
+	return flags
+}
+
+// scheduledFlagsAt returns only the scheduled flags of the snapshot as they are at this date.
+func (s Snapshot) scheduledFlagsAt(date time.Time) map[string]flag.Flag {
+	flags := make(map[string]flag.Flag, len(s.scheduledFlags))
+	for key, v1 := range s.scheduledFlags {
+		flags[key] = v1.ActiveStage(date)
+	}
+	return flags
+}
+
+// contentHash returns the hash identifying a flag file and its format.
+func contentHash(content []byte, fileFormat string) [sha256.Size]byte {
+	h := sha256.New()
+	_, _ = h.Write([]byte(strings.ToLower(fileFormat)))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write(content)
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
@@ -0,0 +1,123 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thomaspoignant/go-feature-flag/ffuser"
+	"github.com/thomaspoignant/go-feature-flag/internal/cache"
+	"github.com/thomaspoignant/go-feature-flag/internal/flag"
+	"github.com/thomaspoignant/go-feature-flag/internal/flagv1"
+	"github.com/thomaspoignant/go-feature-flag/testutils/testconvert"
+)
+
+func TestSnapshot_AllFlags(t *testing.T) {
+	tests := []struct {
+		name  string
+		param map[string]flag.Flag
+		want  map[string]flag.Flag
+	}{
+		{
+			name: "all with 1 flag",
+			param: map[string]flag.Flag{
+				"test": &flagv1.FlagData{
+					Percentage: testconvert.Float64(40),
+					True:       testconvert.Interface("true"),
+					False:      testconvert.Interface("false"),
+					Default:    testconvert.Interface("default"),
+				},
+			},
+			want: map[string]flag.Flag{
+				"test": &flagv1.FlagData{
+					Percentage: testconvert.Float64(40),
+					True:       testconvert.Interface("true"),
+					False:      testconvert.Interface("false"),
+					Default:    testconvert.Interface("default"),
+				},
+			},
+		},
+		{
+			name: "all with multiple flags",
+			param: map[string]flag.Flag{
+				"test": &flagv1.FlagData{
+					Percentage: testconvert.Float64(40),
+					True:       testconvert.Interface("true"),
+					False:      testconvert.Interface("false"),
+					Default:    testconvert.Interface("default"),
+				},
+				"test1": &flagv1.FlagData{
+					Percentage: testconvert.Float64(30),
+					True:       testconvert.Interface(true),
+					False:      testconvert.Interface(false),
+					Default:    testconvert.Interface(false),
+				},
+			},
+			want: map[string]flag.Flag{
+				"test": &flagv1.FlagData{
+					Percentage: testconvert.Float64(40),
+					True:       testconvert.Interface("true"),
+					False:      testconvert.Interface("false"),
+					Default:    testconvert.Interface("default"),
+				},
+				"test1": &flagv1.FlagData{
+					Percentage: testconvert.Float64(30),
+					True:       testconvert.Interface(true),
+					False:      testconvert.Interface(false),
+					Default:    testconvert.Interface(false),
+				},
+			},
+		},
+		{
+			name:  "empty",
+			param: map[string]flag.Flag{},
+			want:  map[string]flag.Flag{},
+		},
+		{
+			name:  "nil",
+			param: nil,
+			want:  map[string]flag.Flag{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := cache.NewSnapshot(tt.param, nil)
+			assert.Equal(t, tt.want, s.AllFlags())
+		})
+	}
+}
+
+func TestSnapshot_ScheduledFlags(t *testing.T) {
+	stepDate := time.Now().Add(-1 * time.Second)
+	scheduledFlag := &flagv1.FlagData{
+		Percentage: testconvert.Float64(0),
+		True:       testconvert.Interface("true"),
+		False:      testconvert.Interface("false"),
+		Default:    testconvert.Interface("default"),
+		Rollout: &flagv1.Rollout{Scheduled: &flagv1.ScheduledRollout{Steps: []flagv1.ScheduledStep{
+			{FlagData: flagv1.FlagData{Percentage: testconvert.Float64(100)}, Date: &stepDate},
+		}}},
+	}
+	assert.NoError(t, scheduledFlag.Init())
+	staticFlag := &flagv1.FlagData{
+		Percentage: testconvert.Float64(100),
+		True:       testconvert.Interface("true"),
+		False:      testconvert.Interface("false"),
+		Default:    testconvert.Interface("default"),
+	}
+	s := cache.NewSnapshot(map[string]flag.Flag{"scheduled": scheduledFlag, "static": staticFlag}, nil)
+
+	// the scheduled flags are returned in their active stage, the others are shared.
+	scheduled, err := s.GetFlag("scheduled")
+	assert.NoError(t, err)
+	assert.Equal(t, "100.00", scheduled.GetRawValues()["Percentage"])
+	assert.Equal(t, "100.00", s.AllFlags()["scheduled"].GetRawValues()["Percentage"])
+	static, err := s.GetFlag("static")
+	assert.NoError(t, err)
+	assert.Same(t, staticFlag, static)
+	assert.Same(t, staticFlag, s.AllFlags()["static"])
+
+	value, _ := scheduled.Value("scheduled", ffuser.NewUser("random-key"), flag.EvaluationContext{})
+	assert.Equal(t, "true", value)
+	assert.Equal(t, float64(0), *scheduledFlag.Percentage, "the flag in the snapshot is untouched")
+}
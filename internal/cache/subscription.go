@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"sync"
+)
+
+// Subscription calls the listener of a subscriber in a dedicated goroutine.
+// The calls are queued and executed in order, a slow listener never blocks the caller of Push.
+type Subscription struct {
+	onUnsubscribe func(s *Subscription)
+
+	mutex   sync.Mutex
+	cond    *sync.Cond
+	queue   []func()
+	closed  bool
+	stopped chan struct{}
+	done    chan struct{}
+}
+
+// NewSubscription creates a Subscription, onUnsubscribe is called when the subscription is stopped.
+func NewSubscription(onUnsubscribe func(s *Subscription)) *Subscription {
+	s := &Subscription{
+		onUnsubscribe: onUnsubscribe,
+		stopped:       make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	s.cond = sync.NewCond(&s.mutex)
+	go s.run()
+	return s
+}
+
+// Unsubscribe stops the delivery of the changes, the changes not delivered yet are dropped.
+// It does not wait for the listener to return, it can be called from the listener.
+func (s *Subscription) Unsubscribe() {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		return
+	}
+	s.closed = true
+	s.queue = nil
+	close(s.stopped)
+	s.cond.Signal()
+	s.mutex.Unlock()
+
+	if s.onUnsubscribe != nil {
+		s.onUnsubscribe(s)
+	}
+}
+
+// Stopped is closed when Unsubscribe is called, a listener waiting for something should stop.
+func (s *Subscription) Stopped() <-chan struct{} {
+	return s.stopped
+}
+
+// Done is closed when the listener will not be called anymore.
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Push queues a call to the listener of the subscriber.
+func (s *Subscription) Push(call func()) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return
+	}
+	s.queue = append(s.queue, call)
+	s.cond.Signal()
+}
+
+// run executes the calls in the queue until the subscription is closed.
+func (s *Subscription) run() {
+	defer close(s.done)
+	for {
+		s.mutex.Lock()
+		for len(s.queue) == 0 && !s.closed {
+			s.cond.Wait()
+		}
+		if s.closed {
+			s.mutex.Unlock()
+			return
+		}
+		call := s.queue[0]
+		s.queue = s.queue[1:]
+		s.mutex.Unlock()
+
+		call()
+	}
+}
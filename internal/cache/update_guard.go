@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/thomaspoignant/go-feature-flag/ffnotifier"
+	"github.com/thomaspoignant/go-feature-flag/internal/flag"
+)
+
+// UpdateGuard refuses the updates of the flag file that look destructive,
+// the flags in the cache are kept when an update is refused.
+type UpdateGuard struct {
+	// MaxDeletedFlagsPercentage refuses an update deleting more than this percentage of the flags
+	// in the cache, 0 disables the check.
+	MaxDeletedFlagsPercentage float64
+
+	// RefuseEmptyFlagFile refuses an update without any flag.
+	RefuseEmptyFlagFile bool
+}
+
+// check returns the reason why the update from oldFlags to newFlags is refused, nil if the update is accepted.
+func (g UpdateGuard) check(oldFlags map[string]flag.Flag, newFlags map[string]flag.Flag) *ffnotifier.UpdateRejection {
+	deleted := make([]string, 0)
+	for key := range oldFlags {
+		if _, ok := newFlags[key]; !ok {
+			deleted = append(deleted, key)
+		}
+	}
+	sort.Strings(deleted)
+
+	if g.RefuseEmptyFlagFile && len(newFlags) == 0 {
+		return &ffnotifier.UpdateRejection{Reason: "the flag file does not contain any flag", DeletedFlags: deleted}
+	}
+
+	if g.MaxDeletedFlagsPercentage <= 0 || len(oldFlags) == 0 {
+		return nil
+	}
+	percentage := float64(len(deleted)) * 100 / float64(len(oldFlags))
+	if percentage > g.MaxDeletedFlagsPercentage {
+		return &ffnotifier.UpdateRejection{
+			Reason: fmt.Sprintf("the update deletes %d of the %d flags (%.2f%%), the maximum allowed is %.2f%%",
+				len(deleted), len(oldFlags), percentage, g.MaxDeletedFlagsPercentage),
+			DeletedFlags: deleted,
+		}
+	}
+	return nil
+}
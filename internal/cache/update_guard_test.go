@@ -0,0 +1,141 @@
+package cache_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thomaspoignant/go-feature-flag/ffnotifier"
+	"github.com/thomaspoignant/go-feature-flag/internal/cache"
+)
+
+// rejectionNotifier records the rejections of the flag file.
+type rejectionNotifier struct {
+	mutex      sync.Mutex
+	rejections []ffnotifier.UpdateRejection
+}
+
+func (n *rejectionNotifier) Notify(_ ffnotifier.DiffCache, wg *sync.WaitGroup) {
+	wg.Done()
+}
+
+func (n *rejectionNotifier) NotifyRejection(rejection ffnotifier.UpdateRejection, wg *sync.WaitGroup) {
+	defer wg.Done()
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.rejections = append(n.rejections, rejection)
+}
+
+const guardInitialFlags = `flag-a:
+  true: true
+  false: false
+  default: false
+flag-b:
+  true: true
+  false: false
+  default: false
+flag-c:
+  true: true
+  false: false
+  default: false
+flag-d:
+  true: true
+  false: false
+  default: false
+`
+
+func Test_cacheManagerImpl_UpdateGuard(t *testing.T) {
+	tests := []struct {
+		name          string
+		guard         cache.UpdateGuard
+		loadedFlags   string
+		wantErr       error
+		wantRejection *ffnotifier.UpdateRejection
+		wantFlags     int
+	}{
+		{
+			name:        "empty flag file accepted without guard",
+			loadedFlags: ``,
+			wantFlags:   0,
+		},
+		{
+			name:        "empty flag file refused",
+			guard:       cache.UpdateGuard{RefuseEmptyFlagFile: true},
+			loadedFlags: ``,
+			wantErr:     cache.ErrUpdateRefused,
+			wantRejection: &ffnotifier.UpdateRejection{
+				Reason:       "the flag file does not contain any flag",
+				DeletedFlags: []string{"flag-a", "flag-b", "flag-c", "flag-d"},
+			},
+			wantFlags: 4,
+		},
+		{
+			name:  "too many flags deleted",
+			guard: cache.UpdateGuard{MaxDeletedFlagsPercentage: 50},
+			loadedFlags: `flag-a:
+  true: true
+  false: false
+  default: false
+`,
+			wantErr: cache.ErrUpdateRefused,
+			wantRejection: &ffnotifier.UpdateRejection{
+				Reason:       "the update deletes 3 of the 4 flags (75.00%), the maximum allowed is 50.00%",
+				DeletedFlags: []string{"flag-b", "flag-c", "flag-d"},
+			},
+			wantFlags: 4,
+		},
+		{
+			name:  "flags deleted under the limit",
+			guard: cache.UpdateGuard{MaxDeletedFlagsPercentage: 50},
+			loadedFlags: `flag-a:
+  true: true
+  false: false
+  default: false
+flag-b:
+  true: true
+  false: false
+  default: false
+`,
+			wantFlags: 2,
+		},
+		{
+			name:        "invalid flag refused",
+			loadedFlags: "flag-a:\n  targets:\n    Treu: [a]\n  true: true\n  false: false\n  default: false\n",
+			wantRejection: &ffnotifier.UpdateRejection{
+				Reason: "invalid flag flag-a: unknown variation Treu in the targets",
+			},
+			wantFlags: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notifier := &rejectionNotifier{}
+			fCache := cache.NewWithUpdateGuard(cache.NewNotificationService([]ffnotifier.Notifier{notifier}), tt.guard)
+			assert.NoError(t, fCache.UpdateCache([]byte(guardInitialFlags), "yaml"))
+			previousUpdate := fCache.GetLatestUpdateDate()
+
+			err := fCache.UpdateCache([]byte(tt.loadedFlags), "yaml")
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+			if tt.wantRejection != nil {
+				assert.Error(t, err)
+				assert.Equal(t, previousUpdate, fCache.GetLatestUpdateDate(), "the cache should not be updated")
+
+				// the same flag file is refused again without a new notification.
+				assert.Equal(t, err, fCache.UpdateCache([]byte(tt.loadedFlags), "yaml"))
+			} else {
+				assert.NoError(t, err)
+			}
+			flags, _ := fCache.AllFlags()
+			assert.Len(t, flags, tt.wantFlags)
+
+			fCache.Close()
+			if tt.wantRejection != nil {
+				assert.Equal(t, []ffnotifier.UpdateRejection{*tt.wantRejection}, notifier.rejections)
+			} else {
+				assert.Empty(t, notifier.rejections)
+			}
+		})
+	}
+}
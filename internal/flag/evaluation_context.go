@@ -0,0 +1,42 @@
+package flag
+
+import (
+	"time"
+
+	"github.com/thomaspoignant/go-feature-flag/ffuser"
+	"github.com/thomaspoignant/go-feature-flag/internal/utils"
+)
+
+// EvaluationContext contains everything needed to evaluate a flag in addition to the user.
+type EvaluationContext struct {
+	// Environment is the environment of the application, it is available as "env" in the rules.
+	Environment string
+
+	// Segments are the user segments that can be referenced in the rules.
+	Segments Segments
+}
+
+// Attributes returns the map used to evaluate the rules, it contains the attributes of the user
+// and the built-in variables:
+//   - env: the environment of the application (only if it is set).
+//   - now: the time of the evaluation.
+//   - flagName: the name of the flag evaluated.
+//
+// The env variable replaces the custom attribute of the user with the same name, now and flagName
+// do not: a custom attribute with one of these names is kept.
+func (c EvaluationContext) Attributes(flagName string, user ffuser.User) map[string]interface{} {
+	attributes := utils.UserToMap(user)
+	if c.Environment != "" {
+		attributes["env"] = c.Environment
+	}
+	setIfAbsent(attributes, "now", time.Now())
+	setIfAbsent(attributes, "flagName", flagName)
+	return attributes
+}
+
+// setIfAbsent sets the value of the key only if the map does not contain it yet.
+func setIfAbsent(attributes map[string]interface{}, key string, value interface{}) {
+	if _, ok := attributes[key]; !ok {
+		attributes[key] = value
+	}
+}
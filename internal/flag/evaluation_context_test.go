@@ -0,0 +1,47 @@
+package flag_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thomaspoignant/go-feature-flag/ffuser"
+	"github.com/thomaspoignant/go-feature-flag/internal/flag"
+)
+
+func TestEvaluationContext_Attributes(t *testing.T) {
+	user := ffuser.NewUserBuilder("user-key").
+		AddCustom("address", map[string]interface{}{"country": "france"}).
+		Build()
+
+	before := time.Now()
+	attributes := flag.EvaluationContext{Environment: "dev"}.Attributes("test-flag", user)
+	assert.Equal(t, "user-key", attributes["key"])
+	assert.Equal(t, false, attributes["anonymous"])
+	assert.Equal(t, map[string]interface{}{"country": "france"}, attributes["address"])
+	assert.Equal(t, "dev", attributes["env"])
+	assert.Equal(t, "test-flag", attributes["flagName"])
+	now, ok := attributes["now"].(time.Time)
+	assert.True(t, ok)
+	assert.False(t, now.Before(before))
+
+	attributes = flag.EvaluationContext{}.Attributes("test-flag", user)
+	_, hasEnv := attributes["env"]
+	assert.False(t, hasEnv)
+}
+
+func TestEvaluationContext_AttributesCustomAttributesWithBuiltInNames(t *testing.T) {
+	user := ffuser.NewUserBuilder("user-key").
+		AddCustom("now", "custom now").
+		AddCustom("flagName", "custom flag name").
+		AddCustom("env", "custom env").
+		Build()
+
+	attributes := flag.EvaluationContext{Environment: "dev"}.Attributes("test-flag", user)
+	assert.Equal(t, "custom now", attributes["now"])
+	assert.Equal(t, "custom flag name", attributes["flagName"])
+	assert.Equal(t, "dev", attributes["env"])
+
+	attributes = flag.EvaluationContext{}.Attributes("test-flag", user)
+	assert.Equal(t, "custom env", attributes["env"])
+}
@@ -6,8 +6,9 @@ import (
 
 type Flag interface {
 	// Value is returning the Value associate to the flag (True / False / Default ) based
-	// if the flag apply to the user and environment or not.
-	Value(flagName string, user ffuser.User, environment string) (interface{}, string)
+	// if the flag apply to the user and the evaluation context or not.
+	// The ResolutionDetails explains which variation has been selected and why.
+	Value(flagName string, user ffuser.User, evaluationCtx EvaluationContext) (interface{}, ResolutionDetails)
 
 	// String display correctly a flag with the right formatting
 	String() string
@@ -20,6 +21,10 @@ type Flag interface {
 	// Default: true
 	GetTrackEvents() bool
 
+	// GetClientSideAvailable is the getter of the field ClientSideAvailable
+	// Default: false
+	GetClientSideAvailable() bool
+
 	// GetDisable is the getter for the field Disable
 	// Default: false
 	GetDisable() bool
@@ -30,6 +35,10 @@ type Flag interface {
 	// GetVariationValue return the value of variation from his name
 	GetVariationValue(variationName string) interface{}
 
+	// GetPrerequisites returns the list of flags that must be evaluated to a specific variation
+	// before evaluating this flag.
+	GetPrerequisites() []Prerequisite
+
 	// GetRawValues is returning a raw value of the Flag used by the notifiers
 	// We should not have any logic based on these values, this is only to
 	// display  the information.
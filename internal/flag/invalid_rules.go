@@ -0,0 +1,12 @@
+package flag
+
+import "strings"
+
+// InvalidRulesError is returned by Init when some queries of a flag or a segment cannot be parsed.
+// The flag is initialized anyway and these queries never match a user, like the invalid queries of
+// the nikunjy/rules library, so a typo in a rule does not prevent the other flags to be loaded.
+type InvalidRulesError []string
+
+func (e InvalidRulesError) Error() string {
+	return strings.Join(e, ", ")
+}
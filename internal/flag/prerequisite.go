@@ -0,0 +1,18 @@
+package flag
+
+import "fmt"
+
+// Prerequisite is a flag that must be evaluated to a specific variation for the user
+// before evaluating the flag that depends on it.
+type Prerequisite struct {
+	// Key is the name of the flag used as a prerequisite.
+	Key string `json:"key" yaml:"key" toml:"key"`
+
+	// Variation is the name of the variation the prerequisite flag must return for this user.
+	Variation string `json:"variation" yaml:"variation" toml:"variation"`
+}
+
+// String display correctly a prerequisite
+func (p Prerequisite) String() string {
+	return fmt.Sprintf("%s=\"%s\"", p.Key, p.Variation)
+}
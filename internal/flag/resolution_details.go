@@ -0,0 +1,84 @@
+package flag
+
+// ResolutionReason is the reason explaining why a variation has been selected for a user.
+type ResolutionReason string
+
+const (
+	// ReasonDefault is used when no rule applies to the user and the default variation is served.
+	ReasonDefault ResolutionReason = "DEFAULT"
+
+	// ReasonTargetMatch is used when the user key is in the targets of the flag.
+	ReasonTargetMatch ResolutionReason = "TARGETING_MATCH"
+
+	// ReasonRuleMatch is used when a rule of the flag applies to the user.
+	ReasonRuleMatch ResolutionReason = "RULE_MATCH"
+
+	// ReasonPercentage is used when the flag applies to all the users and the variation
+	// is selected only based on the percentage.
+	ReasonPercentage ResolutionReason = "PERCENTAGE"
+
+	// ReasonDisabled is used when the flag is disabled.
+	ReasonDisabled ResolutionReason = "DISABLED"
+
+	// ReasonExperimentNotRunning is used when the flag has an experimentation rollout
+	// and the experimentation is not running.
+	ReasonExperimentNotRunning ResolutionReason = "EXPERIMENT_NOT_RUNNING"
+
+	// ReasonPrerequisiteFailed is used when a prerequisite of the flag is not evaluated to the
+	// expected variation for the user, the default variation is served.
+	ReasonPrerequisiteFailed ResolutionReason = "PREREQUISITE_FAILED"
+
+	// ReasonFlagNotFound is used when the flag does not exist, the SDK default value is served.
+	ReasonFlagNotFound ResolutionReason = "FLAG_NOT_FOUND"
+
+	// ReasonTypeMismatch is used when the value of the flag is not of the type expected by the
+	// variation function, the SDK default value is served.
+	ReasonTypeMismatch ResolutionReason = "TYPE_MISMATCH"
+
+	// ReasonOffline is used when the SDK is in offline mode, the SDK default value is served.
+	ReasonOffline ResolutionReason = "OFFLINE"
+
+	// ReasonError is used when the flag cannot be evaluated because of an unexpected error
+	// (ex: the flags are not loaded yet), the SDK default value is served.
+	ReasonError ResolutionReason = "ERROR"
+)
+
+// ErrorCode identifies the error that prevented the evaluation of the flag.
+type ErrorCode string
+
+const (
+	// ErrorCodeFlagNotFound is used when the flag does not exist.
+	ErrorCodeFlagNotFound ErrorCode = "FLAG_NOT_FOUND"
+
+	// ErrorCodeTypeMismatch is used when the value of the flag is not of the expected type.
+	ErrorCodeTypeMismatch ErrorCode = "TYPE_MISMATCH"
+
+	// ErrorCodeGeneral is used for the other errors.
+	ErrorCodeGeneral ErrorCode = "GENERAL"
+)
+
+// ResolutionDetails contains the details of the evaluation of a flag for a user.
+type ResolutionDetails struct {
+	// Variant is the name of the variation served to the user.
+	Variant string
+
+	// Reason is explaining why this variation has been selected.
+	Reason ResolutionReason
+
+	// RuleIndex is the index of the targeting rule that applied to the user.
+	// It is nil if the variation was not selected by a targeting rule.
+	RuleIndex *int
+
+	// RuleName is the name of the targeting rule that applied to the user.
+	// It is nil if the rule has no name or if the variation was not selected by a targeting rule.
+	RuleName *string
+
+	// BucketingKeyFallback is true if the flag has a bucketing key configured, but the attribute
+	// is missing for this user, in that case the user key has been used to bucket the user.
+	// It is only reported when the bucket decides the variation (ex: not for a percentage of 100%).
+	BucketingKeyFallback bool
+
+	// PrerequisiteKey is the key of the prerequisite flag that failed.
+	// It is nil if the reason is not ReasonPrerequisiteFailed.
+	PrerequisiteKey *string
+}
@@ -0,0 +1,98 @@
+package flag
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/thomaspoignant/go-feature-flag/internal/query"
+)
+
+// Segment is a named group of users that can be referenced in the rules with
+// the operator segment "<segment name>".
+// A user is part of the segment if his key is in the list of keys or if he matches the query.
+type Segment struct {
+	// Query (optional) is the query used to select the users of the segment.
+	Query *string `json:"query,omitempty" yaml:"query,omitempty" toml:"query,omitempty"`
+
+	// Keys (optional) is an explicit list of user keys that are part of the segment.
+	Keys []string `json:"keys,omitempty" yaml:"keys,omitempty" toml:"keys,omitempty"`
+
+	// compiledQuery is the parsed Query, it is built by Init.
+	compiledQuery *query.Query
+}
+
+// Contains returns true if the user represented by his attributes is part of the segment.
+func (s *Segment) Contains(attributes map[string]interface{}) bool {
+	for _, key := range s.Keys {
+		if key == attributes["key"] {
+			return true
+		}
+	}
+	if s.getQuery() == "" {
+		return false
+	}
+	if s.compiledQuery != nil {
+		return s.compiledQuery.Evaluate(attributes, nil)
+	}
+	return query.Evaluate(s.getQuery(), attributes, nil)
+}
+
+// Validate is checking that the segment is consistent.
+func (s *Segment) Validate() error {
+	if s.getQuery() == "" && len(s.Keys) == 0 {
+		return errors.New("a segment should have a query or a list of keys")
+	}
+	return nil
+}
+
+// Init is parsing the query of the segment, it should be called once the segment is loaded.
+// A segment cannot reference another segment in its query, a query that cannot be parsed never matches
+// and is returned in an InvalidRulesError.
+func (s *Segment) Init() error {
+	if s.getQuery() == "" {
+		return nil
+	}
+	compiledQuery, err := query.Parse(s.getQuery())
+	if err != nil {
+		s.compiledQuery = query.Never(s.getQuery())
+		return InvalidRulesError{fmt.Sprintf("invalid query: %v", err)}
+	}
+	if len(compiledQuery.Segments()) > 0 {
+		return errors.New("a segment cannot reference another segment")
+	}
+	s.compiledQuery = compiledQuery
+	return nil
+}
+
+// String display correctly a segment
+func (s Segment) String() string {
+	toString := []string{}
+	if s.getQuery() != "" {
+		toString = append(toString, fmt.Sprintf("query=\"%s\"", s.getQuery()))
+	}
+	if len(s.Keys) > 0 {
+		toString = append(toString, fmt.Sprintf("keys=[%s]", strings.Join(s.Keys, ", ")))
+	}
+	return strings.Join(toString, ", ")
+}
+
+// getQuery is the getter of the field Query
+func (s *Segment) getQuery() string {
+	if s.Query == nil {
+		return ""
+	}
+	return *s.Query
+}
+
+// Segments are all the segments available in the flag file, the key of the map is the name of the segment.
+type Segments map[string]Segment
+
+// IsInSegment returns true if the user is part of the segment, an unknown segment never contains the user.
+func (s Segments) IsInSegment(name string, attributes map[string]interface{}) bool {
+	segment, ok := s[name]
+	if !ok {
+		return false
+	}
+	return segment.Contains(attributes)
+}
@@ -0,0 +1,76 @@
+package flag
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Targets is the list of users forced to a variation, the key of the map is the name of the
+// variation and the value is the list of the user keys that always receive this variation.
+type Targets map[string][]string
+
+// Index returns the variation targeted for each user key, it allows to find
+// the variation of a user without iterating over all the lists.
+func (t Targets) Index() map[string]string {
+	index := make(map[string]string)
+	for _, variation := range t.sortedVariations() {
+		for _, key := range t[variation] {
+			index[key] = variation
+		}
+	}
+	return index
+}
+
+// Validate is checking that every variation exists and that a user key is targeted only once.
+// isVariation returns true if the flag has a variation with this name.
+func (t Targets) Validate(isVariation func(name string) bool) error {
+	targeted := make(map[string]string)
+	for _, variation := range t.sortedVariations() {
+		if !isVariation(variation) {
+			return fmt.Errorf("unknown variation %s in the targets", variation)
+		}
+		for _, key := range t[variation] {
+			if previous, ok := targeted[key]; ok && previous != variation {
+				return fmt.Errorf("user %s is targeted by the variations %s and %s", key, previous, variation)
+			}
+			targeted[key] = variation
+		}
+	}
+	return nil
+}
+
+// String display correctly the targets sorted by variation name.
+func (t Targets) String() string {
+	targets := make([]string, 0, len(t))
+	for _, variation := range t.sortedVariations() {
+		targets = append(targets, fmt.Sprintf("%s=[%s]", variation, strings.Join(t[variation], ", ")))
+	}
+	return strings.Join(targets, ", ")
+}
+
+func (t Targets) sortedVariations() []string {
+	variations := make([]string, 0, len(t))
+	for variation := range t {
+		variations = append(variations, variation)
+	}
+	sort.Strings(variations)
+	return variations
+}
+
+// TargetedVariation returns the variation targeted for the user key.
+// The index is used if available, otherwise we look in the lists of user keys.
+func TargetedVariation(targets Targets, index map[string]string, userKey string) (string, bool) {
+	if index != nil {
+		variation, ok := index[userKey]
+		return variation, ok
+	}
+	for _, variation := range targets.sortedVariations() {
+		for _, key := range targets[variation] {
+			if key == userKey {
+				return variation, true
+			}
+		}
+	}
+	return "", false
+}
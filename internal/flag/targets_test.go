@@ -0,0 +1,31 @@
+package flag_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thomaspoignant/go-feature-flag/internal/flag"
+)
+
+func TestTargets_Index(t *testing.T) {
+	targets := flag.Targets{"A": {"user-1", "user-2"}, "B": {"user-3"}}
+	assert.Equal(t, map[string]string{"user-1": "A", "user-2": "A", "user-3": "B"}, targets.Index())
+	assert.Equal(t, map[string]string{}, flag.Targets{}.Index())
+}
+
+func TestTargets_String(t *testing.T) {
+	targets := flag.Targets{"B": {"user-3"}, "A": {"user-1", "user-2"}}
+	assert.Equal(t, "A=[user-1, user-2], B=[user-3]", targets.String())
+}
+
+func TestTargetedVariation(t *testing.T) {
+	targets := flag.Targets{"A": {"user-1"}, "B": {"user-2"}}
+	for _, index := range []map[string]string{nil, targets.Index()} {
+		variation, ok := flag.TargetedVariation(targets, index, "user-2")
+		assert.True(t, ok)
+		assert.Equal(t, "B", variation)
+
+		_, ok = flag.TargetedVariation(targets, index, "user-3")
+		assert.False(t, ok)
+	}
+}
@@ -45,6 +45,24 @@ func (a AllFlags) MarshalJSON() ([]byte, error) {
 	return json.Marshal(res)
 }
 
+// UnmarshalJSON is reading the JSON produced by MarshalJSON,
+// the Failed field of the flags is not part of the JSON and stays false.
+func (a *AllFlags) UnmarshalJSON(data []byte) error {
+	var res struct {
+		Flags map[string]FlagState `json:"flags"`
+		Valid bool                 `json:"valid"`
+	}
+	if err := json.Unmarshal(data, &res); err != nil {
+		return err
+	}
+	a.flags = res.Flags
+	if a.flags == nil {
+		a.flags = map[string]FlagState{}
+	}
+	a.valid = res.Valid
+	return nil
+}
+
 // IsValid is a getter to know if the AllFlags object is valid.
 func (a *AllFlags) IsValid() bool {
 	return a.valid
@@ -2,6 +2,8 @@ package flagstate
 
 import (
 	"time"
+
+	"github.com/thomaspoignant/go-feature-flag/internal/flag"
 )
 
 // NewFlagState is creating a state for a flag.
@@ -22,9 +24,16 @@ func NewFlagState(
 
 // FlagState represents the state of an individual feature flag, with regard to a specific user, when it was called.
 type FlagState struct {
-	Value         interface{} `json:"value"`
+	// Value is the value of the flag for the user, it is omitted if the user has not been exposed to the flag
+	// and the values of these flags are not requested.
+	Value         interface{} `json:"value,omitempty"`
 	Timestamp     int64       `json:"timestamp"`
 	VariationType string      `json:"variationType"`
 	TrackEvents   bool        `json:"trackEvents"`
 	Failed        bool        `json:"-"`
+
+	// Reason, ErrorCode and Version are set only if the details of the evaluation are requested.
+	Reason    flag.ResolutionReason `json:"reason,omitempty"`
+	ErrorCode flag.ErrorCode        `json:"errorCode,omitempty"`
+	Version   float64               `json:"version,omitempty"`
 }
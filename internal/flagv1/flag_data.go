@@ -1,14 +1,16 @@
 package flagv1
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/nikunjy/rules/parser"
 	"github.com/thomaspoignant/go-feature-flag/ffuser"
+	"github.com/thomaspoignant/go-feature-flag/internal/flag"
+	"github.com/thomaspoignant/go-feature-flag/internal/query"
 	"github.com/thomaspoignant/go-feature-flag/internal/utils"
 )
 
@@ -37,10 +39,39 @@ type FlagData struct {
 	// Default is the value return by the flag if not apply to the user (rule is evaluated to false).
 	Default *interface{} `json:"default,omitempty" yaml:"default,omitempty" toml:"default,omitempty"`
 
+	// BucketingKey (optional) is the name of the user custom attribute used to bucket the users in
+	// the percentages, by default the user key is used.
+	// If the attribute is missing for a user, we fall back to the user key.
+	BucketingKey *string `json:"bucketingKey,omitempty" yaml:"bucketingKey,omitempty" toml:"bucketingKey,omitempty"` // nolint: lll
+
+	// Seed (optional) replaces the flag name in the hash used to bucket the users in the percentages.
+	// It allows to rename the flag without reshuffling the users, to reshuffle the cohorts between 2
+	// experimentations or to align several flags on the same cohort.
+	Seed *string `json:"seed,omitempty" yaml:"seed,omitempty" toml:"seed,omitempty"`
+
+	// Prerequisites (optional) is the list of flags that must be evaluated to a specific variation
+	// for the user, if one of them is not satisfied the default variation is served.
+	Prerequisites []flag.Prerequisite `json:"prerequisites,omitempty" yaml:"prerequisites,omitempty" toml:"prerequisites,omitempty"` // nolint: lll
+
+	// Targets (optional) are the users forced to a variation, the key is the name of the variation
+	// (True, False or Default) and the value is the list of user keys.
+	// The targets are evaluated before the rules.
+	Targets flag.Targets `json:"targets,omitempty" yaml:"targets,omitempty" toml:"targets,omitempty"`
+
+	// Targeting is an ordered list of rules evaluated before the Rule field.
+	// The first rule that applies to the user is used, if no rule applies we evaluate
+	// the Rule and Percentage fields.
+	Targeting []Rule `json:"targeting,omitempty" yaml:"targeting,omitempty" toml:"targeting,omitempty"`
+
 	// TrackEvents is false if you don't want to export the data in your data exporter.
 	// Default value is true
 	TrackEvents *bool `json:"trackEvents,omitempty" yaml:"trackEvents,omitempty" toml:"trackEvents,omitempty"`
 
+	// ClientSideAvailable is true if the flag can be sent to a client-side application (ex: a browser),
+	// it allows to filter the flags returned by AllFlagsStateWithOptions.
+	// Default value is false
+	ClientSideAvailable *bool `json:"clientSideAvailable,omitempty" yaml:"clientSideAvailable,omitempty" toml:"clientSideAvailable,omitempty"` // nolint: lll
+
 	// Disable is true if the flag is disabled.
 	Disable *bool `json:"disable,omitempty" yaml:"disable,omitempty" toml:"disable,omitempty"`
 
@@ -52,28 +83,147 @@ type FlagData struct {
 	// The version is manually managed when you configure your flags and it is used to display the information
 	// in the notifications and data collection.
 	Version *float64 `json:"version,omitempty" yaml:"version,omitempty" toml:"version,omitempty"`
+
+	// compiledRule is the parsed Rule, it is built by Init.
+	compiledRule *query.Query
+
+	// targetsIndex is the variation of each targeted user key, it is built by Init.
+	targetsIndex map[string]string
+
+	// stages are the flag with the steps of the scheduled rollout applied, they are built by Init.
+	stages []scheduledStage
+}
+
+// scheduledStage is the flag as it is from the date of a step of the scheduled rollout.
+type scheduledStage struct {
+	// step is the index of the step of the scheduled rollout.
+	step int
+	date time.Time
+	flag *FlagData
+}
+
+// Init is preparing the flag for the evaluation, it should be called once the flag is loaded.
+// The queries are parsed only once here, the queries that cannot be parsed never match and
+// are returned in a flag.InvalidRulesError, any other error means the flag cannot be used.
+func (f *FlagData) Init() error {
+	var invalidRules flag.InvalidRulesError
+	if f.getRule() != "" {
+		compiledRule, err := query.Parse(f.getRule())
+		if err != nil {
+			invalidRules = append(invalidRules, fmt.Sprintf("invalid rule: %v", err))
+			compiledRule = query.Never(f.getRule())
+		}
+		f.compiledRule = compiledRule
+	}
+
+	for index := range f.Targeting {
+		if err := f.Targeting[index].compile(); err != nil {
+			invalidRules = append(invalidRules, fmt.Sprintf("invalid targeting rule %d: %v", index, err))
+		}
+	}
+
+	if len(f.Targets) > 0 {
+		f.targetsIndex = f.Targets.Index()
+	}
+
+	if f.Rollout != nil && f.Rollout.Scheduled != nil {
+		for index := range f.Rollout.Scheduled.Steps {
+			err := f.Rollout.Scheduled.Steps[index].Init()
+			var stepInvalidRules flag.InvalidRulesError
+			if err != nil && !errors.As(err, &stepInvalidRules) {
+				return fmt.Errorf("invalid scheduled step %d: %v", index, err)
+			}
+			for _, invalidRule := range stepInvalidRules {
+				invalidRules = append(invalidRules, fmt.Sprintf("invalid scheduled step %d: %s", index, invalidRule))
+			}
+		}
+	}
+	f.stages = f.buildStages()
+	// the stages are validated like the flag, an invalid step is detected before being active.
+	for _, stage := range f.stages {
+		if err := stage.flag.Validate(); err != nil {
+			return fmt.Errorf("invalid scheduled step %d: %v", stage.step, err)
+		}
+	}
+
+	if len(invalidRules) > 0 {
+		return invalidRules
+	}
+	return nil
+}
+
+// Validate is checking that the flag is consistent, the targets can only use the
+// variations True, False and Default.
+func (f *FlagData) Validate() error {
+	return f.Targets.Validate(func(name string) bool {
+		return name == VariationTrue || name == VariationFalse || name == VariationDefault
+	})
 }
 
 // Value is returning the Value associate to the flag (True / False / Default ) based
 // if the toggle apply to the user or not.
-func (f *FlagData) Value(flagName string, user ffuser.User, environment string) (interface{}, string) {
-	f.updateFlagStage()
+func (f *FlagData) Value(
+	flagName string, user ffuser.User, evaluationCtx flag.EvaluationContext) (interface{}, flag.ResolutionDetails) {
+	f = f.ActiveStage(time.Now())
 	if f.isExperimentationOver() {
 		// if we have an experimentation that has not started or that is finished we use the default value.
-		return f.getDefault(), VariationDefault
+		return f.getDefault(), flag.ResolutionDetails{Variant: VariationDefault, Reason: flag.ReasonExperimentNotRunning}
 	}
 
-	if f.evaluateRule(user, environment) {
-		if f.isInPercentage(flagName, user) {
+	if f.GetDisable() {
+		return f.getDefault(), flag.ResolutionDetails{Variant: VariationDefault, Reason: flag.ReasonDisabled}
+	}
+
+	if variation, ok := flag.TargetedVariation(f.Targets, f.targetsIndex, user.GetKey()); ok {
+		return f.GetVariationValue(variation), flag.ResolutionDetails{Variant: variation, Reason: flag.ReasonTargetMatch}
+	}
+
+	bucketingKey, fallback := utils.BucketingKey(user, f.getBucketingKey())
+	bucket := utils.Bucket(flagName, f.getSeed(), bucketingKey)
+	if len(f.Targeting) > 0 {
+		userMap := evaluationCtx.Attributes(flagName, user)
+		for index, rule := range f.Targeting {
+			if !rule.isApplicable(userMap, evaluationCtx.Segments) {
+				continue
+			}
+			ruleIndex := index
+			details := flag.ResolutionDetails{
+				Reason:               flag.ReasonRuleMatch,
+				RuleIndex:            &ruleIndex,
+				RuleName:             rule.Name,
+				BucketingKeyFallback: fallback && isBucketed(rule.getPercentage()*percentageMultiplier),
+			}
+			if isUserInPercentage(bucket, rule.getPercentage()*percentageMultiplier) {
+				details.Variant = VariationTrue
+				return f.getTrue(), details
+			}
+			details.Variant = VariationFalse
+			return f.getFalse(), details
+		}
+	}
+
+	if f.evaluateRule(flagName, user, evaluationCtx) {
+		reason := flag.ReasonRuleMatch
+		if f.getRule() == "" {
+			reason = flag.ReasonPercentage
+		}
+
+		details := flag.ResolutionDetails{
+			Reason:               reason,
+			BucketingKeyFallback: fallback && isBucketed(f.getActualPercentage()),
+		}
+		if f.isInPercentage(bucket) {
 			// Rule applied and user in the cohort.
-			return f.getTrue(), VariationTrue
+			details.Variant = VariationTrue
+			return f.getTrue(), details
 		}
 		// Rule applied and user not in the cohort.
-		return f.getFalse(), VariationFalse
+		details.Variant = VariationFalse
+		return f.getFalse(), details
 	}
 
 	// Default value is used if the rule does not applied to the user.
-	return f.getDefault(), VariationDefault
+	return f.getDefault(), flag.ResolutionDetails{Variant: VariationDefault, Reason: flag.ReasonDefault}
 }
 
 func (f *FlagData) isExperimentationOver() bool {
@@ -83,13 +233,18 @@ func (f *FlagData) isExperimentationOver() bool {
 			(f.Rollout.Experimentation.End != nil && now.After(*f.Rollout.Experimentation.End)))
 }
 
-// isInPercentage check if the user is in the cohort for the toggle.
-func (f *FlagData) isInPercentage(flagName string, user ffuser.User) bool {
-	percentage := int32(f.getActualPercentage())
+// isInPercentage check if the bucket of the user is in the cohort for the toggle.
+func (f *FlagData) isInPercentage(bucket uint32) bool {
+	return isUserInPercentage(bucket, f.getActualPercentage())
+}
+
+// isUserInPercentage check if the bucket of the user is in the cohort for a percentage.
+// The percentage is expected with the percentageMultiplier.
+func isUserInPercentage(bucket uint32, percentage float64) bool {
 	maxPercentage := uint32(100 * percentageMultiplier)
 
 	// <= 0%
-	if percentage <= 0 {
+	if int32(percentage) <= 0 {
 		return false
 	}
 	// >= 100%
@@ -97,12 +252,18 @@ func (f *FlagData) isInPercentage(flagName string, user ffuser.User) bool {
 		return true
 	}
 
-	hashID := utils.Hash(flagName+user.GetKey()) % maxPercentage
-	return hashID < uint32(percentage)
+	return bucket < uint32(percentage)
+}
+
+// isBucketed returns true if the percentage splits the users, with 0% or 100% the bucket
+// of the user is not used.
+// The percentage is expected with the percentageMultiplier.
+func isBucketed(percentage float64) bool {
+	return int32(percentage) > 0 && uint32(percentage) < uint32(100*percentageMultiplier)
 }
 
 // evaluateRule is checking if the rule can apply to a specific user.
-func (f *FlagData) evaluateRule(user ffuser.User, environment string) bool {
+func (f *FlagData) evaluateRule(flagName string, user ffuser.User, evaluationCtx flag.EvaluationContext) bool {
 	// Flag disable we cannot apply it.
 	if f.GetDisable() {
 		return false
@@ -114,11 +275,11 @@ func (f *FlagData) evaluateRule(user ffuser.User, environment string) bool {
 	}
 
 	// Evaluate the rule on the user.
-	userMap := utils.UserToMap(user)
-	if environment != "" {
-		userMap["env"] = environment
+	userMap := evaluationCtx.Attributes(flagName, user)
+	if f.compiledRule != nil {
+		return f.compiledRule.Evaluate(userMap, evaluationCtx.Segments)
 	}
-	return parser.Evaluate(f.getRule(), userMap)
+	return query.Evaluate(f.getRule(), userMap, evaluationCtx.Segments)
 }
 
 // string display correctly a flag
@@ -128,6 +289,21 @@ func (f FlagData) String() string {
 	if f.getRule() != "" {
 		toString = append(toString, fmt.Sprintf("rule=\"%s\"", f.getRule()))
 	}
+	if f.getBucketingKey() != "" {
+		toString = append(toString, fmt.Sprintf("bucketingKey=\"%s\"", f.getBucketingKey()))
+	}
+	if f.getSeed() != "" {
+		toString = append(toString, fmt.Sprintf("seed=\"%s\"", f.getSeed()))
+	}
+	if len(f.Prerequisites) > 0 {
+		toString = append(toString, fmt.Sprintf("prerequisites=[%s]", f.prerequisitesToString()))
+	}
+	if len(f.Targets) > 0 {
+		toString = append(toString, fmt.Sprintf("targets=[%v]", f.Targets))
+	}
+	for index, rule := range f.Targeting {
+		toString = append(toString, fmt.Sprintf("targeting[%d]=[%v]", index, rule))
+	}
 	toString = append(toString, fmt.Sprintf("true=\"%v\"", f.getTrue()))
 	toString = append(toString, fmt.Sprintf("false=\"%v\"", f.getFalse()))
 	toString = append(toString, fmt.Sprintf("default=\"%v\"", f.getDefault()))
@@ -137,6 +313,10 @@ func (f FlagData) String() string {
 		toString = append(toString, fmt.Sprintf("trackEvents=\"%v\"", f.GetTrackEvents()))
 	}
 
+	if f.ClientSideAvailable != nil {
+		toString = append(toString, fmt.Sprintf("clientSideAvailable=\"%v\"", f.GetClientSideAvailable()))
+	}
+
 	if f.Version != nil {
 		toString = append(toString, fmt.Sprintf("version=%s", strconv.FormatFloat(f.GetVersion(), 'f', -1, 64)))
 	}
@@ -161,12 +341,13 @@ func (f *FlagData) getActualPercentage() float64 {
 	}
 	// Expand percentage with the percentageMultiplier
 	initialPercentage := f.Rollout.Progressive.Percentage.Initial * percentageMultiplier
-	if f.Rollout.Progressive.Percentage.End == 0 {
-		f.Rollout.Progressive.Percentage.End = 100
+	end := f.Rollout.Progressive.Percentage.End
+	if end == 0 {
+		end = 100
 	}
-	endPercentage := f.Rollout.Progressive.Percentage.End * percentageMultiplier
+	endPercentage := end * percentageMultiplier
 
-	if f.Rollout.Progressive.Percentage.Initial > f.Rollout.Progressive.Percentage.End {
+	if f.Rollout.Progressive.Percentage.Initial > end {
 		return flagPercentage
 	}
 
@@ -188,28 +369,66 @@ func (f *FlagData) getActualPercentage() float64 {
 	return currentPercentage
 }
 
-func (f *FlagData) updateFlagStage() {
-	if f.Rollout == nil || f.Rollout.Scheduled == nil || len(f.Rollout.Scheduled.Steps) == 0 {
-		// no update required because no scheduled rollout configuration
-		return
+// ActiveStage returns the flag as it is at this date, with the steps of the scheduled rollout applied.
+// The flag itself is never modified, the stages are computed by Init.
+func (f *FlagData) ActiveStage(now time.Time) *FlagData {
+	stages := f.stages
+	if stages == nil {
+		// Init has not been called, the stages are computed for this call only.
+		stages = f.buildStages()
 	}
 
-	now := time.Now()
-	for _, step := range f.Rollout.Scheduled.Steps {
-		// if the step has no date we ignore it
-		if step.Date == nil {
-			continue
-		}
-
-		// as soon as we have a step in the future we stop the updates
-		if now.Before(*step.Date) {
+	active := f
+	for _, stage := range stages {
+		// as soon as we have a step in the future we stop
+		if now.Before(stage.date) {
 			break
 		}
+		active = stage.flag
+	}
+	return active
+}
 
-		if step.Date != nil && now.After(*step.Date) {
-			f.mergeChanges(step)
+// Stages returns the flag as it is after each step of the scheduled rollout, in the order of the steps.
+func (f *FlagData) Stages() []*FlagData {
+	stages := f.stages
+	if stages == nil {
+		stages = f.buildStages()
+	}
+	flags := make([]*FlagData, 0, len(stages))
+	for _, stage := range stages {
+		flags = append(flags, stage.flag)
+	}
+	return flags
+}
+
+// IsScheduled returns true if the flag has a scheduled rollout, its active stage changes over time.
+func (f *FlagData) IsScheduled() bool {
+	return f.Rollout != nil && f.Rollout.Scheduled != nil && len(f.Rollout.Scheduled.Steps) > 0
+}
+
+// buildStages applies the steps of the scheduled rollout one after the other,
+// each stage is the flag as it is from the date of its step.
+func (f *FlagData) buildStages() []scheduledStage {
+	stages := []scheduledStage{}
+	if !f.IsScheduled() {
+		return stages
+	}
+
+	current := *f
+	// a stage is final, the steps are not applied again on it.
+	current.stages = []scheduledStage{}
+	for index, step := range f.Rollout.Scheduled.Steps {
+		// if the step has no date we ignore it
+		if step.Date == nil {
+			continue
 		}
+		next := current
+		next.mergeChanges(step)
+		stages = append(stages, scheduledStage{step: index, date: *step.Date, flag: &next})
+		current = next
 	}
+	return stages
 }
 
 // mergeChanges will check every changes on the flag and apply them to the current configuration.
@@ -229,11 +448,31 @@ func (f *FlagData) mergeChanges(stepFlag ScheduledStep) {
 	if stepFlag.TrackEvents != nil {
 		f.TrackEvents = stepFlag.TrackEvents
 	}
+	if stepFlag.ClientSideAvailable != nil {
+		f.ClientSideAvailable = stepFlag.ClientSideAvailable
+	}
 	if stepFlag.Percentage != nil {
 		f.Percentage = stepFlag.Percentage
 	}
 	if stepFlag.Rule != nil {
 		f.Rule = stepFlag.Rule
+		f.compiledRule = stepFlag.compiledRule
+	}
+	if stepFlag.BucketingKey != nil {
+		f.BucketingKey = stepFlag.BucketingKey
+	}
+	if stepFlag.Seed != nil {
+		f.Seed = stepFlag.Seed
+	}
+	if stepFlag.Prerequisites != nil {
+		f.Prerequisites = stepFlag.Prerequisites
+	}
+	if stepFlag.Targets != nil {
+		f.Targets = stepFlag.Targets
+		f.targetsIndex = stepFlag.targetsIndex
+	}
+	if stepFlag.Targeting != nil {
+		f.Targeting = stepFlag.Targeting
 	}
 	if stepFlag.Rollout != nil {
 		f.Rollout = stepFlag.Rollout
@@ -259,6 +498,22 @@ func (f *FlagData) getPercentage() float64 {
 	return *f.Percentage
 }
 
+// getBucketingKey is the getter of the field BucketingKey
+func (f *FlagData) getBucketingKey() string {
+	if f.BucketingKey == nil {
+		return ""
+	}
+	return *f.BucketingKey
+}
+
+// getSeed is the getter of the field Seed
+func (f *FlagData) getSeed() string {
+	if f.Seed == nil {
+		return ""
+	}
+	return *f.Seed
+}
+
 // GetTrue is the getter of the field True
 func (f *FlagData) getTrue() interface{} {
 	if f.True == nil {
@@ -291,6 +546,14 @@ func (f *FlagData) GetTrackEvents() bool {
 	return *f.TrackEvents
 }
 
+// GetClientSideAvailable is the getter of the field ClientSideAvailable
+func (f *FlagData) GetClientSideAvailable() bool {
+	if f.ClientSideAvailable == nil {
+		return false
+	}
+	return *f.ClientSideAvailable
+}
+
 // GetDisable is the getter for the field Disable
 func (f *FlagData) GetDisable() bool {
 	if f.Disable == nil {
@@ -312,6 +575,20 @@ func (f *FlagData) GetVersion() float64 {
 	return *f.Version
 }
 
+// prerequisitesToString display the prerequisites of the flag.
+func (f *FlagData) prerequisitesToString() string {
+	prerequisites := make([]string, 0, len(f.Prerequisites))
+	for _, prerequisite := range f.Prerequisites {
+		prerequisites = append(prerequisites, prerequisite.String())
+	}
+	return strings.Join(prerequisites, ", ")
+}
+
+// GetPrerequisites is the getter for the field Prerequisites
+func (f *FlagData) GetPrerequisites() []flag.Prerequisite {
+	return f.Prerequisites
+}
+
 // GetVariationValue return the value of variation from his name
 func (f *FlagData) GetVariationValue(variationName string) interface{} {
 	switch variationName {
@@ -334,6 +611,16 @@ func (f *FlagData) GetRawValues() map[string]string {
 	rawValues := make(map[string]string)
 	rawValues["Rule"] = f.getRule()
 	rawValues["Percentage"] = fmt.Sprintf("%.2f", f.getPercentage())
+	rawValues["BucketingKey"] = f.getBucketingKey()
+	rawValues["Seed"] = f.getSeed()
+
+	targeting := make([]string, 0, len(f.Targeting))
+	for _, rule := range f.Targeting {
+		targeting = append(targeting, rule.String())
+	}
+	rawValues["Targets"] = f.Targets.String()
+	rawValues["Targeting"] = strings.Join(targeting, "\n")
+	rawValues["Prerequisites"] = f.prerequisitesToString()
 
 	if f.getRollout() == nil {
 		rawValues["Rollout"] = ""
@@ -344,6 +631,10 @@ func (f *FlagData) GetRawValues() map[string]string {
 	rawValues["False"] = convertNilEmpty(f.getFalse())
 	rawValues["Default"] = convertNilEmpty(f.getDefault())
 	rawValues["TrackEvents"] = fmt.Sprintf("%t", f.GetTrackEvents())
+	rawValues["ClientSideAvailable"] = ""
+	if f.ClientSideAvailable != nil {
+		rawValues["ClientSideAvailable"] = fmt.Sprintf("%t", f.GetClientSideAvailable())
+	}
 	rawValues["Disable"] = fmt.Sprintf("%t", f.GetDisable())
 	rawValues["Version"] = fmt.Sprintf("%v", f.GetVersion())
 	return rawValues
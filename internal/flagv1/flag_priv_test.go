@@ -7,6 +7,8 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/thomaspoignant/go-feature-flag/ffuser"
+	"github.com/thomaspoignant/go-feature-flag/internal/flag"
+	"github.com/thomaspoignant/go-feature-flag/internal/utils"
 	"github.com/thomaspoignant/go-feature-flag/testutils/testconvert"
 )
 
@@ -111,7 +113,7 @@ func TestFlag_evaluateRule(t *testing.T) {
 				False:      testconvert.Interface(tt.fields.False),
 			}
 
-			got := f.evaluateRule(tt.args.user, tt.args.env)
+			got := f.evaluateRule("test-flag", tt.args.user, flag.EvaluationContext{Environment: tt.args.env})
 			assert.Equal(t, tt.want, got)
 		})
 	}
@@ -256,7 +258,7 @@ func TestFlag_isInPercentage(t *testing.T) {
 				False:      testconvert.Interface(tt.fields.False),
 			}
 
-			got := f.isInPercentage(tt.args.flagName, tt.args.user)
+			got := f.isInPercentage(utils.Bucket(tt.args.flagName, "", tt.args.user.GetKey()))
 			assert.Equal(t, tt.want, got)
 		})
 	}
@@ -457,3 +459,31 @@ func TestFlag_getPercentage(t *testing.T) {
 		})
 	}
 }
+
+func TestFlag_Init(t *testing.T) {
+	f := FlagData{
+		Rule:      testconvert.String(`key eq "user-1"`),
+		Targeting: []Rule{{Query: testconvert.String("beta eq true")}, {}},
+		Rollout: &Rollout{
+			Scheduled: &ScheduledRollout{
+				Steps: []ScheduledStep{
+					{
+						FlagData: FlagData{Rule: testconvert.String(`key eq "user-2"`)},
+						Date:     testconvert.Time(time.Now().Add(-1 * time.Minute)),
+					},
+				},
+			},
+		},
+	}
+	assert.NoError(t, f.Init())
+	assert.Equal(t, `key eq "user-1"`, f.compiledRule.String())
+	assert.Equal(t, "beta eq true", f.Targeting[0].compiledQuery.String())
+	assert.Nil(t, f.Targeting[1].compiledQuery)
+
+	// the compiled rule of the scheduled step replaces the one of the flag in the active stage
+	stage := f.ActiveStage(time.Now())
+	assert.Equal(t, `key eq "user-2"`, stage.compiledRule.String())
+	assert.True(t, stage.evaluateRule("test-flag", ffuser.NewUser("user-2"), flag.EvaluationContext{}))
+	assert.False(t, stage.evaluateRule("test-flag", ffuser.NewUser("user-1"), flag.EvaluationContext{}))
+	assert.Equal(t, `key eq "user-1"`, f.compiledRule.String(), "the flag itself is never modified")
+}
@@ -2,6 +2,7 @@ package flagv1_test
 
 import (
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -337,13 +338,221 @@ func TestFlag_value(t *testing.T) {
 				Rollout:    &tt.fields.Rollout,
 			}
 
-			got, variationType := f.Value(tt.args.flagName, tt.args.user, "")
+			got, resolutionDetails := f.Value(tt.args.flagName, tt.args.user, flag.EvaluationContext{})
 			assert.Equal(t, tt.want.value, got)
-			assert.Equal(t, tt.want.variationType, variationType)
+			assert.Equal(t, tt.want.variationType, resolutionDetails.Variant)
 		})
 	}
 }
 
+func TestFlag_Targeting(t *testing.T) {
+	f := &flagv1.FlagData{
+		Targeting: []flagv1.Rule{
+			{
+				Name:       testconvert.String("internal users"),
+				Query:      testconvert.String("email ew \"@example.com\""),
+				Percentage: testconvert.Float64(100),
+			},
+			{
+				Query:      testconvert.String("beta eq true"),
+				Percentage: testconvert.Float64(0),
+			},
+		},
+		Rule:       testconvert.String("key eq \"user-key\""),
+		Percentage: testconvert.Float64(100),
+		True:       testconvert.Interface("true"),
+		False:      testconvert.Interface("false"),
+		Default:    testconvert.Interface("default"),
+	}
+
+	tests := []struct {
+		name    string
+		user    ffuser.User
+		value   interface{}
+		details flag.ResolutionDetails
+	}{
+		{
+			name:  "first rule match",
+			user:  ffuser.NewUserBuilder("user-key").AddCustom("email", "john@example.com").AddCustom("beta", true).Build(),
+			value: "true",
+			details: flag.ResolutionDetails{
+				Variant:   flagv1.VariationTrue,
+				Reason:    flag.ReasonRuleMatch,
+				RuleIndex: testconvert.Int(0),
+				RuleName:  testconvert.String("internal users"),
+			},
+		},
+		{
+			name:  "second rule match",
+			user:  ffuser.NewUserBuilder("user-key").AddCustom("beta", true).Build(),
+			value: "false",
+			details: flag.ResolutionDetails{
+				Variant:   flagv1.VariationFalse,
+				Reason:    flag.ReasonRuleMatch,
+				RuleIndex: testconvert.Int(1),
+			},
+		},
+		{
+			name:    "no targeting match use the rule field",
+			user:    ffuser.NewUser("user-key"),
+			value:   "true",
+			details: flag.ResolutionDetails{Variant: flagv1.VariationTrue, Reason: flag.ReasonRuleMatch},
+		},
+		{
+			name:    "nothing match",
+			user:    ffuser.NewUser("other-key"),
+			value:   "default",
+			details: flag.ResolutionDetails{Variant: flagv1.VariationDefault, Reason: flag.ReasonDefault},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, details := f.Value("test-flag", tt.user, flag.EvaluationContext{})
+			assert.Equal(t, tt.value, value)
+			assert.Equal(t, tt.details, details)
+		})
+	}
+}
+
+func TestFlag_TargetingScheduledRollout(t *testing.T) {
+	f := &flagv1.FlagData{
+		True:    testconvert.Interface("true"),
+		False:   testconvert.Interface("false"),
+		Default: testconvert.Interface("default"),
+		Rule:    testconvert.String("key eq \"not-a-key\""),
+		Rollout: &flagv1.Rollout{
+			Scheduled: &flagv1.ScheduledRollout{
+				Steps: []flagv1.ScheduledStep{
+					{
+						FlagData: flagv1.FlagData{
+							Targeting: []flagv1.Rule{
+								{Query: testconvert.String("beta eq true"), Percentage: testconvert.Float64(100)},
+							},
+						},
+						Date: testconvert.Time(time.Now().Add(-1 * time.Minute)),
+					},
+				},
+			},
+		},
+	}
+
+	v, details := f.Value("test-flag", ffuser.NewUserBuilder("user-key").AddCustom("beta", true).Build(), flag.EvaluationContext{})
+	assert.Equal(t, "true", v)
+	assert.Equal(t, flag.ReasonRuleMatch, details.Reason)
+	assert.Equal(t, 0, *details.RuleIndex)
+}
+
+func TestFlag_ResolutionReason(t *testing.T) {
+	tests := []struct {
+		name   string
+		flag   flagv1.FlagData
+		reason flag.ResolutionReason
+	}{
+		{
+			name: "disabled flag",
+			flag: flagv1.FlagData{
+				Disable: testconvert.Bool(true),
+			},
+			reason: flag.ReasonDisabled,
+		},
+		{
+			name: "experimentation not started",
+			flag: flagv1.FlagData{
+				Rollout: &flagv1.Rollout{
+					Experimentation: &flagv1.Experimentation{Start: testconvert.Time(time.Now().Add(1 * time.Minute))},
+				},
+			},
+			reason: flag.ReasonExperimentNotRunning,
+		},
+		{
+			name: "no rule",
+			flag: flagv1.FlagData{
+				Percentage: testconvert.Float64(50),
+			},
+			reason: flag.ReasonPercentage,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, details := tt.flag.Value("test-flag", ffuser.NewUser("user-key"), flag.EvaluationContext{})
+			assert.Equal(t, tt.reason, details.Reason)
+		})
+	}
+}
+
+func TestFlag_BucketingKey(t *testing.T) {
+	f := flagv1.FlagData{
+		Percentage:   testconvert.Float64(50),
+		True:         testconvert.Interface("true"),
+		False:        testconvert.Interface("false"),
+		Default:      testconvert.Interface("default"),
+		BucketingKey: testconvert.String("companyId"),
+	}
+
+	// all the users of the same company get the same variation
+	_, reference := f.Value("test-flag", ffuser.NewUserBuilder("user-0").AddCustom("companyId", "go-ff").Build(), flag.EvaluationContext{})
+	assert.False(t, reference.BucketingKeyFallback)
+	for i := 1; i < 50; i++ {
+		user := ffuser.NewUserBuilder(fmt.Sprintf("user-%d", i)).AddCustom("companyId", "go-ff").Build()
+		_, details := f.Value("test-flag", user, flag.EvaluationContext{})
+		assert.Equal(t, reference.Variant, details.Variant)
+		assert.False(t, details.BucketingKeyFallback)
+	}
+
+	// missing attribute, we fall back to the user key
+	user := ffuser.NewUser("user-key")
+	_, details := f.Value("test-flag", user, flag.EvaluationContext{})
+	assert.True(t, details.BucketingKeyFallback)
+	f.BucketingKey = nil
+	_, withoutBucketingKey := f.Value("test-flag", user, flag.EvaluationContext{})
+	assert.Equal(t, withoutBucketingKey.Variant, details.Variant)
+	assert.False(t, withoutBucketingKey.BucketingKeyFallback)
+
+	// with 0% or 100% the bucket is not used, there is no fallback to report.
+	f.BucketingKey = testconvert.String("companyId")
+	for _, percentage := range []float64{0, 100} {
+		f.Percentage = testconvert.Float64(percentage)
+		_, details = f.Value("test-flag", user, flag.EvaluationContext{})
+		assert.False(t, details.BucketingKeyFallback, "percentage %v", percentage)
+	}
+	f.Percentage = testconvert.Float64(50)
+	f.Targeting = []flagv1.Rule{{Query: testconvert.String(`key eq "user-key"`), Percentage: testconvert.Float64(100)}}
+	_, details = f.Value("test-flag", user, flag.EvaluationContext{})
+	assert.Equal(t, flagv1.VariationTrue, details.Variant)
+	assert.False(t, details.BucketingKeyFallback, "a rule at 100% does not use the bucket")
+}
+
+func TestFlag_Seed(t *testing.T) {
+	newFlag := func(seed *string) flagv1.FlagData {
+		return flagv1.FlagData{
+			Percentage: testconvert.Float64(50),
+			True:       testconvert.Interface("true"),
+			False:      testconvert.Interface("false"),
+			Default:    testconvert.Interface("default"),
+			Seed:       seed,
+		}
+	}
+
+	sameCohort := func(flagName1 string, f1 flagv1.FlagData, flagName2 string, f2 flagv1.FlagData) bool {
+		for i := 0; i < 100; i++ {
+			user := ffuser.NewUser(fmt.Sprintf("user-%d", i))
+			_, d1 := f1.Value(flagName1, user, flag.EvaluationContext{})
+			_, d2 := f2.Value(flagName2, user, flag.EvaluationContext{})
+			if d1.Variant != d2.Variant {
+				return false
+			}
+		}
+		return true
+	}
+
+	// same seed, renaming the flag does not reshuffle the users
+	assert.True(t, sameCohort("flag-1", newFlag(testconvert.String("seed")), "flag-2", newFlag(testconvert.String("seed"))))
+	// different seeds reshuffle the users
+	assert.False(t, sameCohort("flag-1", newFlag(testconvert.String("seed-1")), "flag-1", newFlag(testconvert.String("seed-2"))))
+	// no seed, the flag name is used
+	assert.False(t, sameCohort("flag-1", newFlag(nil), "flag-2", newFlag(nil)))
+}
+
 func TestFlag_ProgressiveRollout(t *testing.T) {
 	f := &flagv1.FlagData{
 		Percentage: testconvert.Float64(0),
@@ -362,15 +571,15 @@ func TestFlag_ProgressiveRollout(t *testing.T) {
 	flagName := "test-flag"
 
 	// We evaluate the same flag multiple time overtime.
-	v, _ := f.Value(flagName, user, "")
+	v, _ := f.Value(flagName, user, flag.EvaluationContext{})
 	assert.Equal(t, f.GetVariationValue(flagv1.VariationFalse), v)
 
 	time.Sleep(1 * time.Second)
-	v2, _ := f.Value(flagName, user, "")
+	v2, _ := f.Value(flagName, user, flag.EvaluationContext{})
 	assert.Equal(t, f.GetVariationValue(flagv1.VariationFalse), v2)
 
 	time.Sleep(1 * time.Second)
-	v3, _ := f.Value(flagName, user, "")
+	v3, _ := f.Value(flagName, user, flag.EvaluationContext{})
 	assert.Equal(t, f.GetVariationValue(flagv1.VariationTrue), v3)
 }
 
@@ -447,43 +656,44 @@ func TestFlag_ScheduledRollout(t *testing.T) {
 	flagName := "test-flag"
 
 	// We evaluate the same flag multiple time overtime.
-	v, _ := f.Value(flagName, user, "")
+	v, _ := f.Value(flagName, user, flag.EvaluationContext{})
 	assert.Equal(t, f.GetVariationValue(flagv1.VariationFalse), v)
 
 	time.Sleep(1 * time.Second)
 
-	v, _ = f.Value(flagName, user, "")
+	v, _ = f.Value(flagName, user, flag.EvaluationContext{})
 	assert.Equal(t, "True", v)
-	assert.Equal(t, 1.1, f.GetVersion())
+	assert.Equal(t, 1.1, f.ActiveStage(time.Now()).GetVersion())
+	assert.Equal(t, float64(0), f.GetVersion(), "the evaluation does not modify the flag")
 
 	time.Sleep(1 * time.Second)
 
-	v, _ = f.Value(flagName, user, "")
+	v, _ = f.Value(flagName, user, flag.EvaluationContext{})
 	assert.Equal(t, "Default2", v)
 
 	time.Sleep(1 * time.Second)
 
-	v, _ = f.Value(flagName, user, "")
+	v, _ = f.Value(flagName, user, flag.EvaluationContext{})
 	assert.Equal(t, "True2", v)
 
 	time.Sleep(1 * time.Second)
 
-	v, _ = f.Value(flagName, user, "")
+	v, _ = f.Value(flagName, user, flag.EvaluationContext{})
 	assert.Equal(t, "Default2", v)
 
 	time.Sleep(1 * time.Second)
 
-	v, _ = f.Value(flagName, user, "")
+	v, _ = f.Value(flagName, user, flag.EvaluationContext{})
 	assert.Equal(t, "Default2", v)
 
 	time.Sleep(1 * time.Second)
 
-	v, _ = f.Value(flagName, user, "")
+	v, _ = f.Value(flagName, user, flag.EvaluationContext{})
 	assert.Equal(t, "True2", v)
 
 	time.Sleep(1 * time.Second)
 
-	v, _ = f.Value(flagName, user, "")
+	v, _ = f.Value(flagName, user, flag.EvaluationContext{})
 	assert.Equal(t, "Default2", v)
 }
 
@@ -588,15 +798,21 @@ func TestFlag_Getter(t *testing.T) {
 				Rule:        "",
 				Version:     0,
 				RawValues: map[string]string{
-					"Default":     "",
-					"Disable":     "false",
-					"False":       "",
-					"Percentage":  "0.00",
-					"Rollout":     "",
-					"Rule":        "",
-					"TrackEvents": "true",
-					"True":        "",
-					"Version":     "0",
+					"BucketingKey":        "",
+					"Default":             "",
+					"Disable":             "false",
+					"False":               "",
+					"Percentage":          "0.00",
+					"Prerequisites":       "",
+					"Targets":             "",
+					"Rollout":             "",
+					"Rule":                "",
+					"Seed":                "",
+					"Targeting":           "",
+					"TrackEvents":         "true",
+					"ClientSideAvailable": "",
+					"True":                "",
+					"Version":             "0",
 				},
 			},
 		},
@@ -622,15 +838,21 @@ func TestFlag_Getter(t *testing.T) {
 				Rule:        "test",
 				Version:     127,
 				RawValues: map[string]string{
-					"Default":     "14.2",
-					"Disable":     "true",
-					"False":       "13.2",
-					"Percentage":  "90.00",
-					"Rollout":     "",
-					"Rule":        "test",
-					"TrackEvents": "false",
-					"True":        "12.2",
-					"Version":     "127",
+					"BucketingKey":        "",
+					"Default":             "14.2",
+					"Disable":             "true",
+					"False":               "13.2",
+					"Percentage":          "90.00",
+					"Prerequisites":       "",
+					"Targets":             "",
+					"Rollout":             "",
+					"Rule":                "test",
+					"Seed":                "",
+					"Targeting":           "",
+					"TrackEvents":         "false",
+					"ClientSideAvailable": "",
+					"True":                "12.2",
+					"Version":             "127",
 				},
 			},
 		},
@@ -648,3 +870,138 @@ func TestFlag_Getter(t *testing.T) {
 		})
 	}
 }
+
+func TestFlag_Targets(t *testing.T) {
+	f := flagv1.FlagData{
+		Rule:       testconvert.String("beta eq true"),
+		Percentage: testconvert.Float64(100),
+		True:       testconvert.Interface("true"),
+		False:      testconvert.Interface("false"),
+		Default:    testconvert.Interface("default"),
+		Targets: flag.Targets{
+			flagv1.VariationFalse:   {"user-1", "user-2"},
+			flagv1.VariationDefault: {"user-3"},
+		},
+	}
+	assert.NoError(t, f.Init())
+
+	tests := []struct {
+		name        string
+		user        ffuser.User
+		want        interface{}
+		wantVariant string
+		wantReason  flag.ResolutionReason
+	}{
+		{
+			name:        "Targeted user",
+			user:        ffuser.NewUserBuilder("user-2").AddCustom("beta", true).Build(),
+			want:        "false",
+			wantVariant: flagv1.VariationFalse,
+			wantReason:  flag.ReasonTargetMatch,
+		},
+		{
+			name:        "Targeted to the default variation",
+			user:        ffuser.NewUser("user-3"),
+			want:        "default",
+			wantVariant: flagv1.VariationDefault,
+			wantReason:  flag.ReasonTargetMatch,
+		},
+		{
+			name:        "Not targeted user use the rule",
+			user:        ffuser.NewUserBuilder("user-4").AddCustom("beta", true).Build(),
+			want:        "true",
+			wantVariant: flagv1.VariationTrue,
+			wantReason:  flag.ReasonRuleMatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, details := f.Value("test-flag", tt.user, flag.EvaluationContext{})
+			assert.Equal(t, tt.want, value)
+			assert.Equal(t, tt.wantVariant, details.Variant)
+			assert.Equal(t, tt.wantReason, details.Reason)
+		})
+	}
+
+	// targets are not used when the flag is disabled
+	f.Disable = testconvert.Bool(true)
+	_, details := f.Value("test-flag", ffuser.NewUser("user-1"), flag.EvaluationContext{})
+	assert.Equal(t, flag.ReasonDisabled, details.Reason)
+}
+
+func TestFlag_Validate(t *testing.T) {
+	f := flagv1.FlagData{Targets: flag.Targets{flagv1.VariationTrue: {"user-1"}}}
+	assert.NoError(t, f.Validate())
+
+	f.Targets = flag.Targets{"Unknown": {"user-1"}}
+	assert.EqualError(t, f.Validate(), "unknown variation Unknown in the targets")
+}
+
+func TestFlag_ExtendedOperators(t *testing.T) {
+	f := flagv1.FlagData{
+		Rule:       testconvert.String(`appVersion semver_ge "2.1.0" and ip incidr "10.0.0.0/8" and signupDate after "2022-01-01"`),
+		Percentage: testconvert.Float64(100),
+		True:       testconvert.Interface(true),
+		False:      testconvert.Interface(false),
+		Default:    testconvert.Interface(false),
+	}
+	assert.NoError(t, f.Init())
+
+	user := ffuser.NewUserBuilder("user-key").
+		AddCustom("appVersion", "2.10.0").
+		AddCustom("ip", "10.1.2.3").
+		AddCustom("signupDate", "2022-02-01").
+		Build()
+	value, details := f.Value("test-flag", user, flag.EvaluationContext{})
+	assert.Equal(t, true, value)
+	assert.Equal(t, flag.ReasonRuleMatch, details.Reason)
+
+	oldAppUser := ffuser.NewUserBuilder("user-key").
+		AddCustom("appVersion", "2.1.0-rc.1").
+		AddCustom("ip", "10.1.2.3").
+		AddCustom("signupDate", "2022-02-01").
+		Build()
+	value, details = f.Value("test-flag", oldAppUser, flag.EvaluationContext{})
+	assert.Equal(t, false, value)
+	assert.Equal(t, flag.ReasonDefault, details.Reason)
+}
+
+func TestFlag_ConcurrentRolloutEvaluation(t *testing.T) {
+	f := &flagv1.FlagData{
+		Percentage: testconvert.Float64(0),
+		True:       testconvert.Interface("True"),
+		False:      testconvert.Interface("False"),
+		Default:    testconvert.Interface("Default"),
+		Rollout: &flagv1.Rollout{
+			Progressive: &flagv1.Progressive{
+				ReleaseRamp: flagv1.ProgressiveReleaseRamp{
+					Start: testconvert.Time(time.Now().Add(-1 * time.Minute)),
+					End:   testconvert.Time(time.Now().Add(1 * time.Minute)),
+				},
+			},
+			Scheduled: &flagv1.ScheduledRollout{
+				Steps: []flagv1.ScheduledStep{
+					{
+						FlagData: flagv1.FlagData{Version: testconvert.Float64(2)},
+						Date:     testconvert.Time(time.Now().Add(-1 * time.Second)),
+					},
+				},
+			},
+		},
+	}
+	assert.NoError(t, f.Init())
+
+	// the evaluation never modifies the flag, it can be evaluated concurrently.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _ = f.Value("test-flag", ffuser.NewUser(fmt.Sprintf("user-%d", i)), flag.EvaluationContext{})
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, float64(0), f.Rollout.Progressive.Percentage.End)
+	assert.Nil(t, f.Version)
+	assert.Equal(t, float64(2), f.ActiveStage(time.Now()).GetVersion())
+}
@@ -0,0 +1,94 @@
+package flagv1
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/thomaspoignant/go-feature-flag/internal/flag"
+	"github.com/thomaspoignant/go-feature-flag/internal/query"
+)
+
+// Rule is a targeting rule of the flag.
+// Each rule has its own query and its own percentage, the rules are evaluated
+// in order and the first rule that applies to the user is used.
+type Rule struct {
+	// Name (optional) is the name of the rule, it is used to explain which rule applied to the user.
+	Name *string `json:"name,omitempty" yaml:"name,omitempty" toml:"name,omitempty"`
+
+	// Query is the query use to select on which user the rule should apply.
+	// Query format is based on the nikunjy/rules module.
+	// If no query set, the rule apply to all users.
+	Query *string `json:"query,omitempty" yaml:"query,omitempty" toml:"query,omitempty"`
+
+	// Percentage of the users affected by the rule that get the True value,
+	// the others users affected by the rule get the False value.
+	// Default value is 0
+	Percentage *float64 `json:"percentage,omitempty" yaml:"percentage,omitempty" toml:"percentage,omitempty"`
+
+	// compiledQuery is the parsed Query, it is built when the flag is loaded.
+	compiledQuery *query.Query
+}
+
+// compile is parsing the query of the rule, the parsed query is used for every evaluation.
+// A query that cannot be parsed never matches.
+func (r *Rule) compile() error {
+	if r.getQuery() == "" {
+		return nil
+	}
+	compiledQuery, err := query.Parse(r.getQuery())
+	if err != nil {
+		r.compiledQuery = query.Never(r.getQuery())
+		return err
+	}
+	r.compiledQuery = compiledQuery
+	return nil
+}
+
+// isApplicable is checking if the rule applies to the user.
+func (r *Rule) isApplicable(userMap map[string]interface{}, segments flag.Segments) bool {
+	if r.getQuery() == "" {
+		return true
+	}
+	if r.compiledQuery != nil {
+		return r.compiledQuery.Evaluate(userMap, segments)
+	}
+	return query.Evaluate(r.getQuery(), userMap, segments)
+}
+
+// String display correctly a rule
+func (r Rule) String() string {
+	toString := []string{}
+	if r.getName() != "" {
+		toString = append(toString, fmt.Sprintf("name=\"%s\"", r.getName()))
+	}
+	if r.getQuery() != "" {
+		toString = append(toString, fmt.Sprintf("query=\"%s\"", r.getQuery()))
+	}
+	toString = append(toString, fmt.Sprintf("percentage=%d%%", int64(math.Round(r.getPercentage()))))
+	return strings.Join(toString, ", ")
+}
+
+// getName is the getter of the field Name
+func (r *Rule) getName() string {
+	if r.Name == nil {
+		return ""
+	}
+	return *r.Name
+}
+
+// getQuery is the getter of the field Query
+func (r *Rule) getQuery() string {
+	if r.Query == nil {
+		return ""
+	}
+	return *r.Query
+}
+
+// getPercentage is the getter of the field Percentage
+func (r *Rule) getPercentage() float64 {
+	if r.Percentage == nil {
+		return 0
+	}
+	return *r.Percentage
+}
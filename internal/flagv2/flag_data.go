@@ -0,0 +1,348 @@
+package flagv2
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thomaspoignant/go-feature-flag/ffuser"
+	"github.com/thomaspoignant/go-feature-flag/internal/flag"
+	"github.com/thomaspoignant/go-feature-flag/internal/flagv1"
+	"github.com/thomaspoignant/go-feature-flag/internal/utils"
+)
+
+// FlagData describe the fields of a flag using the multi-variation format.
+// Instead of having the fixed True / False / Default values, you can define as many
+// variations as you want and the rules are pointing to a variation by its name.
+type FlagData struct {
+	// Variations are all the values available for this flag, the key of the map is the
+	// name of the variation, and it is used by the rules to select which value to serve.
+	Variations map[string]*interface{} `json:"variations,omitempty" yaml:"variations,omitempty" toml:"variations,omitempty"` // nolint: lll
+
+	// Prerequisites (optional) is the list of flags that must be evaluated to a specific variation
+	// for the user, if one of them is not satisfied the default variation is served.
+	Prerequisites []flag.Prerequisite `json:"prerequisites,omitempty" yaml:"prerequisites,omitempty" toml:"prerequisites,omitempty"` // nolint: lll
+
+	// Targets (optional) are the users forced to a variation, the key is the name of the variation and the
+	// value is the list of user keys. The targets are evaluated before the targeting rules.
+	Targets flag.Targets `json:"targets,omitempty" yaml:"targets,omitempty" toml:"targets,omitempty"`
+
+	// Targeting is the ordered list of rules of the flag.
+	// The rules are evaluated in order and the first rule that applies to the user is used.
+	Targeting []Rule `json:"targeting,omitempty" yaml:"targeting,omitempty" toml:"targeting,omitempty"`
+
+	// DefaultRule is the rule used if no targeting rule applies to the user.
+	DefaultRule *Rule `json:"defaultRule,omitempty" yaml:"defaultRule,omitempty" toml:"defaultRule,omitempty"`
+
+	// BucketingKey (optional) is the name of the user custom attribute used to bucket the users in
+	// the percentage splits, by default the user key is used.
+	// If the attribute is missing for a user, we fall back to the user key.
+	BucketingKey *string `json:"bucketingKey,omitempty" yaml:"bucketingKey,omitempty" toml:"bucketingKey,omitempty"` // nolint: lll
+
+	// Seed (optional) replaces the flag name in the hash used to bucket the users in the percentage splits.
+	// It allows to rename the flag without reshuffling the users, to reshuffle the cohorts between 2
+	// experimentations or to align several flags on the same cohort.
+	Seed *string `json:"seed,omitempty" yaml:"seed,omitempty" toml:"seed,omitempty"`
+
+	// TrackEvents is false if you don't want to export the data in your data exporter.
+	// Default value is true
+	TrackEvents *bool `json:"trackEvents,omitempty" yaml:"trackEvents,omitempty" toml:"trackEvents,omitempty"`
+
+	// ClientSideAvailable is true if the flag can be sent to a client-side application (ex: a browser),
+	// it allows to filter the flags returned by AllFlagsStateWithOptions.
+	// Default value is false
+	ClientSideAvailable *bool `json:"clientSideAvailable,omitempty" yaml:"clientSideAvailable,omitempty" toml:"clientSideAvailable,omitempty"` // nolint: lll
+
+	// Disable is true if the flag is disabled.
+	Disable *bool `json:"disable,omitempty" yaml:"disable,omitempty" toml:"disable,omitempty"`
+
+	// Experimentation (optional) is the time window of the experimentation, when the experimentation
+	// is not running, the flag behaves as if it was disabled.
+	Experimentation *flagv1.Experimentation `json:"experimentation,omitempty" yaml:"experimentation,omitempty" toml:"experimentation,omitempty"` // nolint: lll
+
+	// Version (optional) This field contains the version of the flag.
+	// The version is manually managed when you configure your flags and it is used to display the information
+	// in the notifications and data collection.
+	Version *float64 `json:"version,omitempty" yaml:"version,omitempty" toml:"version,omitempty"`
+
+	// targetsIndex is the variation of each targeted user key, it is built by Init.
+	targetsIndex map[string]string
+}
+
+// Init is preparing the flag for the evaluation, it should be called once the flag is loaded.
+// The queries are parsed only once here, the queries that cannot be parsed never match and
+// are returned in a flag.InvalidRulesError, any other error means the flag cannot be used.
+func (f *FlagData) Init() error {
+	var invalidRules flag.InvalidRulesError
+	for index := range f.Targeting {
+		if err := f.Targeting[index].compile(); err != nil {
+			invalidRules = append(invalidRules, fmt.Sprintf("invalid rule %d: %v", index, err))
+		}
+	}
+	if len(f.Targets) > 0 {
+		f.targetsIndex = f.Targets.Index()
+	}
+
+	if len(invalidRules) > 0 {
+		return invalidRules
+	}
+	return nil
+}
+
+// Value is returning the Value associate to the flag based on the first rule
+// that applies to the user, if no rule applies we use the default rule.
+func (f *FlagData) Value(
+	flagName string, user ffuser.User, evaluationCtx flag.EvaluationContext) (interface{}, flag.ResolutionDetails) {
+	details := f.getResolutionDetails(flagName, user, evaluationCtx)
+	return f.GetVariationValue(details.Variant), details
+}
+
+// getResolutionDetails returns the variation to serve to the user and why we have selected it.
+func (f *FlagData) getResolutionDetails(
+	flagName string, user ffuser.User, evaluationCtx flag.EvaluationContext) flag.ResolutionDetails {
+	if f.GetDisable() {
+		return flag.ResolutionDetails{Variant: f.GetDefaultVariation(), Reason: flag.ReasonDisabled}
+	}
+
+	if !f.isExperimentationRunning() {
+		return flag.ResolutionDetails{Variant: f.GetDefaultVariation(), Reason: flag.ReasonExperimentNotRunning}
+	}
+
+	if variation, ok := flag.TargetedVariation(f.Targets, f.targetsIndex, user.GetKey()); ok {
+		return flag.ResolutionDetails{Variant: variation, Reason: flag.ReasonTargetMatch}
+	}
+
+	userMap := evaluationCtx.Attributes(flagName, user)
+
+	bucketingKey, fallback := utils.BucketingKey(user, f.getBucketingKey())
+	bucket := utils.Bucket(flagName, f.getSeed(), bucketingKey)
+	for index, rule := range f.Targeting {
+		if rule.isApplicable(userMap, evaluationCtx.Segments) {
+			ruleIndex := index
+			return flag.ResolutionDetails{
+				Variant:              rule.evaluate(bucket),
+				Reason:               flag.ReasonRuleMatch,
+				RuleIndex:            &ruleIndex,
+				RuleName:             rule.Name,
+				BucketingKeyFallback: fallback && rule.isBucketed(),
+			}
+		}
+	}
+
+	if f.DefaultRule == nil {
+		return flag.ResolutionDetails{Reason: flag.ReasonDefault}
+	}
+
+	if f.DefaultRule.isPercentageSplit() {
+		return flag.ResolutionDetails{
+			Variant:              f.DefaultRule.evaluate(bucket),
+			Reason:               flag.ReasonPercentage,
+			BucketingKeyFallback: fallback && f.DefaultRule.isBucketed(),
+		}
+	}
+	return flag.ResolutionDetails{Variant: f.DefaultRule.evaluate(bucket), Reason: flag.ReasonDefault}
+}
+
+// isExperimentationRunning is checking if we are in the time window of the experimentation.
+func (f *FlagData) isExperimentationRunning() bool {
+	if f.Experimentation == nil {
+		return true
+	}
+	now := time.Now()
+	return (f.Experimentation.Start == nil || now.After(*f.Experimentation.Start)) &&
+		(f.Experimentation.End == nil || now.Before(*f.Experimentation.End))
+}
+
+// Validate is checking that the flag is consistent, every variation used in the rules
+// should exist in the list of variations.
+func (f *FlagData) Validate() error {
+	if len(f.Variations) == 0 {
+		return errors.New("no variation available")
+	}
+	if f.DefaultRule == nil {
+		return errors.New("missing default rule")
+	}
+	if err := f.DefaultRule.validate(f.Variations); err != nil {
+		return fmt.Errorf("invalid default rule: %v", err)
+	}
+	for index, rule := range f.Targeting {
+		if err := rule.validate(f.Variations); err != nil {
+			return fmt.Errorf("invalid rule %d: %v", index, err)
+		}
+	}
+	return f.Targets.Validate(func(name string) bool {
+		_, ok := f.Variations[name]
+		return ok
+	})
+}
+
+// String display correctly a flag
+func (f FlagData) String() string {
+	toString := []string{}
+	toString = append(toString, fmt.Sprintf("variations=[%s]", f.variationsToString()))
+	if len(f.Prerequisites) > 0 {
+		toString = append(toString, fmt.Sprintf("prerequisites=[%s]", f.prerequisitesToString()))
+	}
+	if len(f.Targets) > 0 {
+		toString = append(toString, fmt.Sprintf("targets=[%v]", f.Targets))
+	}
+	for index, rule := range f.Targeting {
+		toString = append(toString, fmt.Sprintf("targeting[%d]=[%v]", index, rule))
+	}
+	if f.DefaultRule != nil {
+		toString = append(toString, fmt.Sprintf("defaultRule=[%v]", *f.DefaultRule))
+	}
+	if f.getBucketingKey() != "" {
+		toString = append(toString, fmt.Sprintf("bucketingKey=\"%s\"", f.getBucketingKey()))
+	}
+	if f.getSeed() != "" {
+		toString = append(toString, fmt.Sprintf("seed=\"%s\"", f.getSeed()))
+	}
+	if f.Experimentation != nil {
+		toString = append(toString, fmt.Sprintf("experimentation=[%v]", *f.Experimentation))
+	}
+	toString = append(toString, fmt.Sprintf("disable=\"%v\"", f.GetDisable()))
+
+	if f.TrackEvents != nil {
+		toString = append(toString, fmt.Sprintf("trackEvents=\"%v\"", f.GetTrackEvents()))
+	}
+
+	if f.ClientSideAvailable != nil {
+		toString = append(toString, fmt.Sprintf("clientSideAvailable=\"%v\"", f.GetClientSideAvailable()))
+	}
+
+	if f.Version != nil {
+		toString = append(toString, fmt.Sprintf("version=%s", strconv.FormatFloat(f.GetVersion(), 'f', -1, 64)))
+	}
+
+	return strings.Join(toString, ", ")
+}
+
+// variationsToString display the variations sorted by name.
+func (f *FlagData) variationsToString() string {
+	names := make([]string, 0, len(f.Variations))
+	for name := range f.Variations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	variations := make([]string, 0, len(names))
+	for _, name := range names {
+		variations = append(variations, fmt.Sprintf("%s=\"%v\"", name, f.GetVariationValue(name)))
+	}
+	return strings.Join(variations, ", ")
+}
+
+// getBucketingKey is the getter of the field BucketingKey
+func (f *FlagData) getBucketingKey() string {
+	if f.BucketingKey == nil {
+		return ""
+	}
+	return *f.BucketingKey
+}
+
+// getSeed is the getter of the field Seed
+func (f *FlagData) getSeed() string {
+	if f.Seed == nil {
+		return ""
+	}
+	return *f.Seed
+}
+
+// GetTrackEvents is the getter of the field TrackEvents
+func (f *FlagData) GetTrackEvents() bool {
+	if f.TrackEvents == nil {
+		return true
+	}
+	return *f.TrackEvents
+}
+
+// GetClientSideAvailable is the getter of the field ClientSideAvailable
+func (f *FlagData) GetClientSideAvailable() bool {
+	if f.ClientSideAvailable == nil {
+		return false
+	}
+	return *f.ClientSideAvailable
+}
+
+// GetDisable is the getter for the field Disable
+func (f *FlagData) GetDisable() bool {
+	if f.Disable == nil {
+		return false
+	}
+	return *f.Disable
+}
+
+// GetVersion is the getter for the field Version
+func (f *FlagData) GetVersion() float64 {
+	if f.Version == nil {
+		return 0
+	}
+	return *f.Version
+}
+
+// prerequisitesToString display the prerequisites of the flag.
+func (f *FlagData) prerequisitesToString() string {
+	prerequisites := make([]string, 0, len(f.Prerequisites))
+	for _, prerequisite := range f.Prerequisites {
+		prerequisites = append(prerequisites, prerequisite.String())
+	}
+	return strings.Join(prerequisites, ", ")
+}
+
+// GetPrerequisites is the getter for the field Prerequisites
+func (f *FlagData) GetPrerequisites() []flag.Prerequisite {
+	return f.Prerequisites
+}
+
+// GetVariationValue return the value of variation from his name
+func (f *FlagData) GetVariationValue(variationName string) interface{} {
+	value, ok := f.Variations[variationName]
+	if !ok || value == nil {
+		return nil
+	}
+	return *value
+}
+
+// GetDefaultVariation return the name of the variation used by the default rule.
+// If the default rule is a percentage split, there is no default variation.
+func (f *FlagData) GetDefaultVariation() string {
+	if f.DefaultRule == nil {
+		return ""
+	}
+	return f.DefaultRule.getVariation()
+}
+
+func (f *FlagData) GetRawValues() map[string]string {
+	rawValues := make(map[string]string)
+	rawValues["Variations"] = f.variationsToString()
+
+	targeting := make([]string, 0, len(f.Targeting))
+	for _, rule := range f.Targeting {
+		targeting = append(targeting, rule.String())
+	}
+	rawValues["Targets"] = f.Targets.String()
+	rawValues["Targeting"] = strings.Join(targeting, "\n")
+	rawValues["Prerequisites"] = f.prerequisitesToString()
+	rawValues["DefaultRule"] = ""
+	if f.DefaultRule != nil {
+		rawValues["DefaultRule"] = f.DefaultRule.String()
+	}
+	rawValues["BucketingKey"] = f.getBucketingKey()
+	rawValues["Seed"] = f.getSeed()
+	rawValues["Experimentation"] = ""
+	if f.Experimentation != nil {
+		rawValues["Experimentation"] = f.Experimentation.String()
+	}
+	rawValues["TrackEvents"] = fmt.Sprintf("%t", f.GetTrackEvents())
+	rawValues["ClientSideAvailable"] = ""
+	if f.ClientSideAvailable != nil {
+		rawValues["ClientSideAvailable"] = fmt.Sprintf("%t", f.GetClientSideAvailable())
+	}
+	rawValues["Disable"] = fmt.Sprintf("%t", f.GetDisable())
+	rawValues["Version"] = fmt.Sprintf("%v", f.GetVersion())
+	return rawValues
+}
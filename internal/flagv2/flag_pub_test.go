@@ -0,0 +1,486 @@
+package flagv2_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thomaspoignant/go-feature-flag/ffuser"
+	"github.com/thomaspoignant/go-feature-flag/internal/flag"
+	"github.com/thomaspoignant/go-feature-flag/internal/flagv1"
+	"github.com/thomaspoignant/go-feature-flag/internal/flagv2"
+	"github.com/thomaspoignant/go-feature-flag/testutils/testconvert"
+)
+
+func newTestFlag() flagv2.FlagData {
+	return flagv2.FlagData{
+		Variations: map[string]*interface{}{
+			"red":   testconvert.Interface("red"),
+			"blue":  testconvert.Interface("blue"),
+			"green": testconvert.Interface("green"),
+		},
+		Targeting: []flagv2.Rule{
+			{
+				Name:      testconvert.String("internal users"),
+				Query:     testconvert.String("email ew \"@example.com\""),
+				Variation: testconvert.String("blue"),
+			},
+			{
+				Query:     testconvert.String("env eq \"dev\""),
+				Variation: testconvert.String("green"),
+			},
+		},
+		DefaultRule: &flagv2.Rule{Variation: testconvert.String("red")},
+	}
+}
+
+func TestFlagData_Value(t *testing.T) {
+	tests := []struct {
+		name          string
+		flag          func() flagv2.FlagData
+		user          ffuser.User
+		environment   string
+		wantValue     interface{}
+		wantVariation string
+	}{
+		{
+			name:          "No rule match use default rule",
+			flag:          newTestFlag,
+			user:          ffuser.NewUser("user-key"),
+			wantValue:     "red",
+			wantVariation: "red",
+		},
+		{
+			name:          "First rule match",
+			flag:          newTestFlag,
+			user:          ffuser.NewUserBuilder("user-key").AddCustom("email", "john@example.com").Build(),
+			environment:   "dev",
+			wantValue:     "blue",
+			wantVariation: "blue",
+		},
+		{
+			name:          "Second rule match with environment",
+			flag:          newTestFlag,
+			user:          ffuser.NewUser("user-key"),
+			environment:   "dev",
+			wantValue:     "green",
+			wantVariation: "green",
+		},
+		{
+			name: "Rule without query apply to everyone",
+			flag: func() flagv2.FlagData {
+				f := newTestFlag()
+				f.Targeting = []flagv2.Rule{{Variation: testconvert.String("green")}}
+				return f
+			},
+			user:          ffuser.NewUser("user-key"),
+			wantValue:     "green",
+			wantVariation: "green",
+		},
+		{
+			name: "Disabled flag use default rule",
+			flag: func() flagv2.FlagData {
+				f := newTestFlag()
+				f.Disable = testconvert.Bool(true)
+				return f
+			},
+			user:          ffuser.NewUserBuilder("user-key").AddCustom("email", "john@example.com").Build(),
+			wantValue:     "red",
+			wantVariation: "red",
+		},
+		{
+			name: "Unknown variation return nil",
+			flag: func() flagv2.FlagData {
+				f := newTestFlag()
+				f.DefaultRule = &flagv2.Rule{Variation: testconvert.String("yellow")}
+				return f
+			},
+			user:          ffuser.NewUser("user-key"),
+			wantValue:     nil,
+			wantVariation: "yellow",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := tt.flag()
+			value, resolutionDetails := f.Value("test-flag", tt.user, flag.EvaluationContext{Environment: tt.environment})
+			assert.Equal(t, tt.wantValue, value)
+			assert.Equal(t, tt.wantVariation, resolutionDetails.Variant)
+		})
+	}
+}
+
+func TestFlagData_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		flag    flagv2.FlagData
+		wantErr bool
+	}{
+		{
+			name: "valid flag",
+			flag: newTestFlag(),
+		},
+		{
+			name:    "no variations",
+			flag:    flagv2.FlagData{DefaultRule: &flagv2.Rule{Variation: testconvert.String("red")}},
+			wantErr: true,
+		},
+		{
+			name: "no default rule",
+			flag: flagv2.FlagData{
+				Variations: map[string]*interface{}{"red": testconvert.Interface("red")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "default rule with unknown variation",
+			flag: flagv2.FlagData{
+				Variations:  map[string]*interface{}{"red": testconvert.Interface("red")},
+				DefaultRule: &flagv2.Rule{Variation: testconvert.String("blue")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid percentage split",
+			flag: flagv2.FlagData{
+				Variations: map[string]*interface{}{
+					"A": testconvert.Interface("A"),
+					"B": testconvert.Interface("B"),
+					"C": testconvert.Interface("C"),
+				},
+				DefaultRule: &flagv2.Rule{Percentage: map[string]float64{"A": 33.333, "B": 33.333, "C": 33.334}},
+			},
+		},
+		{
+			name: "percentage split not equal to 100",
+			flag: flagv2.FlagData{
+				Variations: map[string]*interface{}{
+					"A": testconvert.Interface("A"),
+					"B": testconvert.Interface("B"),
+				},
+				DefaultRule: &flagv2.Rule{Percentage: map[string]float64{"A": 50, "B": 40}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "percentage split with unknown variation",
+			flag: flagv2.FlagData{
+				Variations:  map[string]*interface{}{"A": testconvert.Interface("A")},
+				DefaultRule: &flagv2.Rule{Percentage: map[string]float64{"A": 50, "B": 50}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "rule with a variation and a percentage split",
+			flag: flagv2.FlagData{
+				Variations: map[string]*interface{}{
+					"A": testconvert.Interface("A"),
+					"B": testconvert.Interface("B"),
+				},
+				DefaultRule: &flagv2.Rule{
+					Variation:  testconvert.String("A"),
+					Percentage: map[string]float64{"A": 50, "B": 50},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "targeting rule with unknown variation",
+			flag: flagv2.FlagData{
+				Variations:  map[string]*interface{}{"red": testconvert.Interface("red")},
+				Targeting:   []flagv2.Rule{{Variation: testconvert.String("blue")}},
+				DefaultRule: &flagv2.Rule{Variation: testconvert.String("red")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "targets with unknown variation",
+			flag: flagv2.FlagData{
+				Variations:  map[string]*interface{}{"red": testconvert.Interface("red")},
+				Targets:     flag.Targets{"blue": {"user-1"}},
+				DefaultRule: &flagv2.Rule{Variation: testconvert.String("red")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "user targeted by 2 variations",
+			flag: flagv2.FlagData{
+				Variations: map[string]*interface{}{
+					"red":  testconvert.Interface("red"),
+					"blue": testconvert.Interface("blue"),
+				},
+				Targets:     flag.Targets{"red": {"user-1"}, "blue": {"user-2", "user-1"}},
+				DefaultRule: &flagv2.Rule{Variation: testconvert.String("red")},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.flag.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestFlagData_String(t *testing.T) {
+	f := newTestFlag()
+	f.TrackEvents = testconvert.Bool(false)
+	f.Version = testconvert.Float64(1.1)
+	want := "variations=[blue=\"blue\", green=\"green\", red=\"red\"], " +
+		"targeting[0]=[name=\"internal users\", query=\"email ew \"@example.com\"\", variation=\"blue\"], " +
+		"targeting[1]=[query=\"env eq \"dev\"\", variation=\"green\"], " +
+		"defaultRule=[variation=\"red\"], disable=\"false\", trackEvents=\"false\", version=1.1"
+	assert.Equal(t, want, f.String())
+
+	f = flagv2.FlagData{
+		Variations:    map[string]*interface{}{"A": testconvert.Interface("A"), "B": testconvert.Interface("B")},
+		Prerequisites: []flag.Prerequisite{{Key: "new-checkout", Variation: "True"}},
+		DefaultRule:   &flagv2.Rule{Percentage: map[string]float64{"A": 50, "B": 50}},
+		BucketingKey:  testconvert.String("companyId"),
+		Seed:          testconvert.String("experiment-1"),
+	}
+	want = "variations=[A=\"A\", B=\"B\"], prerequisites=[new-checkout=\"True\"], " +
+		"defaultRule=[percentage=[A=50%, B=50%]], bucketingKey=\"companyId\", seed=\"experiment-1\", disable=\"false\""
+	assert.Equal(t, want, f.String())
+}
+
+func TestFlagData_GetRawValues(t *testing.T) {
+	f := newTestFlag()
+	want := map[string]string{
+		"Variations": "blue=\"blue\", green=\"green\", red=\"red\"",
+		"Targeting": "name=\"internal users\", query=\"email ew \"@example.com\"\", variation=\"blue\"\n" +
+			"query=\"env eq \"dev\"\", variation=\"green\"",
+		"DefaultRule":         "variation=\"red\"",
+		"BucketingKey":        "",
+		"Experimentation":     "",
+		"Prerequisites":       "",
+		"Targets":             "",
+		"Seed":                "",
+		"TrackEvents":         "true",
+		"ClientSideAvailable": "",
+		"Disable":             "false",
+		"Version":             "0",
+	}
+	assert.Equal(t, want, f.GetRawValues())
+}
+
+func TestFlagData_ValueResolutionDetails(t *testing.T) {
+	f := newTestFlag()
+
+	_, details := f.Value("test-flag", ffuser.NewUser("user-key"), flag.EvaluationContext{Environment: "dev"})
+	assert.Equal(t, flag.ResolutionDetails{
+		Variant:   "green",
+		Reason:    flag.ReasonRuleMatch,
+		RuleIndex: testconvert.Int(1),
+	}, details)
+
+	_, details = f.Value("test-flag", ffuser.NewUser("user-key"), flag.EvaluationContext{})
+	assert.Equal(t, flag.ResolutionDetails{Variant: "red", Reason: flag.ReasonDefault}, details)
+
+	f.Disable = testconvert.Bool(true)
+	_, details = f.Value("test-flag", ffuser.NewUser("user-key"), flag.EvaluationContext{Environment: "dev"})
+	assert.Equal(t, flag.ResolutionDetails{Variant: "red", Reason: flag.ReasonDisabled}, details)
+}
+
+func TestFlagData_PercentageSplit(t *testing.T) {
+	f := flagv2.FlagData{
+		Variations: map[string]*interface{}{
+			"A": testconvert.Interface("A"),
+			"B": testconvert.Interface("B"),
+			"C": testconvert.Interface("C"),
+		},
+		DefaultRule: &flagv2.Rule{Percentage: map[string]float64{"A": 20, "B": 30, "C": 50}},
+	}
+
+	nbUsers := 10000
+	results := map[string]int{}
+	for i := 0; i < nbUsers; i++ {
+		user := ffuser.NewUser(fmt.Sprintf("user-%d", i))
+		value, details := f.Value("experiment-flag", user, flag.EvaluationContext{})
+		assert.Equal(t, flag.ReasonPercentage, details.Reason)
+		assert.Equal(t, details.Variant, value)
+		results[details.Variant]++
+
+		// the same user always get the same variation
+		_, details2 := f.Value("experiment-flag", user, flag.EvaluationContext{})
+		assert.Equal(t, details.Variant, details2.Variant)
+	}
+
+	assert.InDelta(t, 0.2, float64(results["A"])/float64(nbUsers), 0.02)
+	assert.InDelta(t, 0.3, float64(results["B"])/float64(nbUsers), 0.02)
+	assert.InDelta(t, 0.5, float64(results["C"])/float64(nbUsers), 0.02)
+}
+
+func TestFlagData_Experimentation(t *testing.T) {
+	f := flagv2.FlagData{
+		Variations: map[string]*interface{}{
+			"A": testconvert.Interface("A"),
+			"B": testconvert.Interface("B"),
+		},
+		Targeting: []flagv2.Rule{
+			{Percentage: map[string]float64{"A": 50, "B": 50}},
+		},
+		DefaultRule: &flagv2.Rule{Variation: testconvert.String("A")},
+	}
+
+	f.Experimentation = &flagv1.Experimentation{
+		Start: testconvert.Time(time.Now().Add(-1 * time.Minute)),
+		End:   testconvert.Time(time.Now().Add(1 * time.Minute)),
+	}
+	_, details := f.Value("experiment-flag", ffuser.NewUser("user-key"), flag.EvaluationContext{})
+	assert.Equal(t, flag.ReasonRuleMatch, details.Reason)
+
+	f.Experimentation = &flagv1.Experimentation{
+		Start: testconvert.Time(time.Now().Add(1 * time.Minute)),
+	}
+	value, details := f.Value("experiment-flag", ffuser.NewUser("user-key"), flag.EvaluationContext{})
+	assert.Equal(t, "A", value)
+	assert.Equal(t, flag.ResolutionDetails{Variant: "A", Reason: flag.ReasonExperimentNotRunning}, details)
+
+	f.Experimentation = &flagv1.Experimentation{
+		End: testconvert.Time(time.Now().Add(-1 * time.Minute)),
+	}
+	_, details = f.Value("experiment-flag", ffuser.NewUser("user-key"), flag.EvaluationContext{})
+	assert.Equal(t, flag.ReasonExperimentNotRunning, details.Reason)
+}
+
+func TestFlagData_BucketingKey(t *testing.T) {
+	f := flagv2.FlagData{
+		Variations: map[string]*interface{}{
+			"A": testconvert.Interface("A"),
+			"B": testconvert.Interface("B"),
+		},
+		DefaultRule:  &flagv2.Rule{Percentage: map[string]float64{"A": 50, "B": 50}},
+		BucketingKey: testconvert.String("deviceId"),
+	}
+
+	// the bucketing is done on the device, whatever the user key is
+	device := ffuser.NewUserBuilder("user-1").AddCustom("deviceId", "device-1").Build()
+	_, reference := f.Value("test-flag", device, flag.EvaluationContext{})
+	assert.False(t, reference.BucketingKeyFallback)
+	for i := 2; i < 50; i++ {
+		user := ffuser.NewUserBuilder(fmt.Sprintf("user-%d", i)).AddCustom("deviceId", "device-1").Build()
+		_, details := f.Value("test-flag", user, flag.EvaluationContext{})
+		assert.Equal(t, reference.Variant, details.Variant)
+	}
+
+	// missing attribute, we fall back to the user key and report it
+	_, details := f.Value("test-flag", ffuser.NewUser("user-key"), flag.EvaluationContext{})
+	assert.Equal(t, flag.ReasonPercentage, details.Reason)
+	assert.True(t, details.BucketingKeyFallback)
+
+	// no split, the bucketing key is not used
+	f.DefaultRule = &flagv2.Rule{Variation: testconvert.String("A")}
+	_, details = f.Value("test-flag", ffuser.NewUser("user-key"), flag.EvaluationContext{})
+	assert.False(t, details.BucketingKeyFallback)
+
+	// a split serving 100% of the users to one variation does not use the bucket
+	f.DefaultRule = &flagv2.Rule{Percentage: map[string]float64{"A": 100, "B": 0}}
+	_, details = f.Value("test-flag", ffuser.NewUser("user-key"), flag.EvaluationContext{})
+	assert.Equal(t, "A", details.Variant)
+	assert.False(t, details.BucketingKeyFallback)
+}
+
+func TestFlagData_Segments(t *testing.T) {
+	f := flagv2.FlagData{
+		Variations: map[string]*interface{}{
+			"A": testconvert.Interface("A"),
+			"B": testconvert.Interface("B"),
+		},
+		Targeting: []flagv2.Rule{
+			{Query: testconvert.String(`not segment "internal-users" and segment "beta-testers"`), Variation: testconvert.String("B")},
+		},
+		DefaultRule: &flagv2.Rule{Variation: testconvert.String("A")},
+	}
+	evaluationCtx := flag.EvaluationContext{
+		Segments: flag.Segments{
+			"beta-testers":   {Query: testconvert.String("beta eq true"), Keys: []string{"user-1"}},
+			"internal-users": {Keys: []string{"user-2"}},
+		},
+	}
+
+	tests := []struct {
+		name string
+		user ffuser.User
+		want string
+	}{
+		{name: "In the segment by key", user: ffuser.NewUser("user-1"), want: "B"},
+		{name: "In the segment by query", user: ffuser.NewUserBuilder("user-3").AddCustom("beta", true).Build(), want: "B"},
+		{name: "Excluded segment", user: ffuser.NewUserBuilder("user-2").AddCustom("beta", true).Build(), want: "A"},
+		{name: "Not in the segment", user: ffuser.NewUser("user-4"), want: "A"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, _ := f.Value("test-flag", tt.user, evaluationCtx)
+			assert.Equal(t, tt.want, value)
+		})
+	}
+
+	// without the segments, the user is never in a segment.
+	value, _ := f.Value("test-flag", ffuser.NewUser("user-1"), flag.EvaluationContext{})
+	assert.Equal(t, "A", value)
+}
+
+func TestFlagData_Targets(t *testing.T) {
+	f := flagv2.FlagData{
+		Variations: map[string]*interface{}{
+			"A": testconvert.Interface("A"),
+			"B": testconvert.Interface("B"),
+		},
+		Targets: flag.Targets{"B": {"user-1"}},
+		Targeting: []flagv2.Rule{
+			{Query: testconvert.String(`key eq "user-1"`), Variation: testconvert.String("A")},
+		},
+		DefaultRule: &flagv2.Rule{Variation: testconvert.String("A")},
+	}
+	assert.NoError(t, f.Validate())
+
+	// the targets are available even if the index has not been built
+	value, details := f.Value("test-flag", ffuser.NewUser("user-1"), flag.EvaluationContext{})
+	assert.Equal(t, "B", value)
+	assert.Equal(t, flag.ResolutionDetails{Variant: "B", Reason: flag.ReasonTargetMatch}, details)
+
+	assert.NoError(t, f.Init())
+	value, details = f.Value("test-flag", ffuser.NewUser("user-1"), flag.EvaluationContext{})
+	assert.Equal(t, "B", value)
+	assert.Equal(t, flag.ReasonTargetMatch, details.Reason)
+
+	value, details = f.Value("test-flag", ffuser.NewUser("user-2"), flag.EvaluationContext{})
+	assert.Equal(t, "A", value)
+	assert.Equal(t, flag.ReasonDefault, details.Reason)
+}
+
+func TestFlagData_BuiltInVariables(t *testing.T) {
+	f := flagv2.FlagData{
+		Variations: map[string]*interface{}{
+			"A": testconvert.Interface("A"),
+			"B": testconvert.Interface("B"),
+		},
+		Targeting: []flagv2.Rule{
+			{
+				Query: testconvert.String(fmt.Sprintf(`now after "%s" and now before "%s" and flagName eq "sale-banner"`,
+					time.Now().Add(-1*time.Hour).Format(time.RFC3339), time.Now().Add(1*time.Hour).Format(time.RFC3339))),
+				Variation: testconvert.String("B"),
+			},
+			{Query: testconvert.String(`address.country eq "france"`), Variation: testconvert.String("B")},
+		},
+		DefaultRule: &flagv2.Rule{Variation: testconvert.String("A")},
+	}
+	assert.NoError(t, f.Init())
+
+	value, _ := f.Value("sale-banner", ffuser.NewUser("user-key"), flag.EvaluationContext{})
+	assert.Equal(t, "B", value, "we are in the time window")
+
+	value, _ = f.Value("other-flag", ffuser.NewUser("user-key"), flag.EvaluationContext{})
+	assert.Equal(t, "A", value)
+
+	user := ffuser.NewUserBuilder("user-key").AddCustom("address", map[string]interface{}{"country": "france"}).Build()
+	value, _ = f.Value("other-flag", user, flag.EvaluationContext{})
+	assert.Equal(t, "B", value, "nested attribute")
+}
@@ -0,0 +1,192 @@
+package flagv2
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/thomaspoignant/go-feature-flag/internal/flag"
+	"github.com/thomaspoignant/go-feature-flag/internal/query"
+	"github.com/thomaspoignant/go-feature-flag/internal/utils"
+)
+
+// percentageMultiplier is the multiplier used to have a bigger range of possibility.
+const percentageMultiplier = float64(1000)
+
+// maxPercentage is the number of buckets available to split the users.
+const maxPercentage = utils.MaxBucket
+
+// Rule is a targeting rule of the flag, it selects which variation to serve.
+// A rule is serving either a single variation or a percentage split between several variations.
+type Rule struct {
+	// Name (optional) is the name of the rule, used to identify it in the logs.
+	Name *string `json:"name,omitempty" yaml:"name,omitempty" toml:"name,omitempty"`
+
+	// Query is the query use to select on which user the rule applies.
+	// Query format is based on the nikunjy/rules module.
+	// If no query set, the rule apply to all users.
+	Query *string `json:"query,omitempty" yaml:"query,omitempty" toml:"query,omitempty"`
+
+	// Variation is the name of the variation served if the rule applies to the user.
+	Variation *string `json:"variation,omitempty" yaml:"variation,omitempty" toml:"variation,omitempty"`
+
+	// Percentage is splitting the users between several variations, the key of the map is
+	// the name of the variation and the value is the percentage of users who get it.
+	// The sum of the percentages must be 100.
+	Percentage map[string]float64 `json:"percentage,omitempty" yaml:"percentage,omitempty" toml:"percentage,omitempty"` // nolint: lll
+
+	// compiledQuery is the parsed Query, it is built when the flag is loaded.
+	compiledQuery *query.Query
+}
+
+// compile is parsing the query of the rule, the parsed query is used for every evaluation.
+// A query that cannot be parsed never matches.
+func (r *Rule) compile() error {
+	if r.getQuery() == "" {
+		return nil
+	}
+	compiledQuery, err := query.Parse(r.getQuery())
+	if err != nil {
+		r.compiledQuery = query.Never(r.getQuery())
+		return err
+	}
+	r.compiledQuery = compiledQuery
+	return nil
+}
+
+// isApplicable is checking if the rule applies to the user.
+func (r *Rule) isApplicable(userMap map[string]interface{}, segments flag.Segments) bool {
+	if r.getQuery() == "" {
+		return true
+	}
+	if r.compiledQuery != nil {
+		return r.compiledQuery.Evaluate(userMap, segments)
+	}
+	return query.Evaluate(r.getQuery(), userMap, segments)
+}
+
+// isPercentageSplit returns true if the rule is splitting the users between several variations.
+func (r *Rule) isPercentageSplit() bool {
+	return len(r.Percentage) > 0
+}
+
+// isBucketed returns true if the bucket of the user decides the variation served,
+// a split giving 100% to one variation serves it to every user.
+func (r *Rule) isBucketed() bool {
+	served := 0
+	for _, percentage := range r.Percentage {
+		if percentage > 0 {
+			served++
+		}
+	}
+	return served > 1
+}
+
+// evaluate returns the name of the variation served by this rule to the user
+// based on the bucket of the user.
+func (r *Rule) evaluate(bucket uint32) string {
+	if !r.isPercentageSplit() {
+		return r.getVariation()
+	}
+
+	// The buckets are attributed to the variations sorted by name, to always have
+	// the same order between 2 evaluations.
+	upperBound := float64(0)
+	variations := r.sortedPercentageVariations()
+	for _, variation := range variations {
+		upperBound += r.Percentage[variation] * percentageMultiplier
+		if float64(bucket) < math.Round(upperBound) {
+			return variation
+		}
+	}
+	// should not happen with a valid rule, we return the last variation of the split.
+	return variations[len(variations)-1]
+}
+
+// validate is checking that the rule is consistent with the variations of the flag.
+func (r *Rule) validate(variations map[string]*interface{}) error {
+	if len(r.Percentage) == 0 {
+		if _, ok := variations[r.getVariation()]; !ok {
+			return fmt.Errorf("unknown variation %s", r.getVariation())
+		}
+		return nil
+	}
+
+	if r.Variation != nil {
+		return errors.New("a rule cannot have a variation and a percentage")
+	}
+
+	total := float64(0)
+	for variation, percentage := range r.Percentage {
+		if _, ok := variations[variation]; !ok {
+			return fmt.Errorf("unknown variation %s", variation)
+		}
+		if percentage < 0 {
+			return fmt.Errorf("negative percentage for variation %s", variation)
+		}
+		total += percentage
+	}
+	if math.Round(total*percentageMultiplier) != float64(maxPercentage) {
+		return fmt.Errorf("the sum of the percentages is %v, it should be 100", total)
+	}
+	return nil
+}
+
+// sortedPercentageVariations returns the name of the variations of the split sorted by name.
+func (r *Rule) sortedPercentageVariations() []string {
+	variations := make([]string, 0, len(r.Percentage))
+	for variation := range r.Percentage {
+		variations = append(variations, variation)
+	}
+	sort.Strings(variations)
+	return variations
+}
+
+// String display correctly a rule
+func (r Rule) String() string {
+	toString := []string{}
+	if r.getName() != "" {
+		toString = append(toString, fmt.Sprintf("name=\"%s\"", r.getName()))
+	}
+	if r.getQuery() != "" {
+		toString = append(toString, fmt.Sprintf("query=\"%s\"", r.getQuery()))
+	}
+	if len(r.Percentage) > 0 {
+		split := make([]string, 0, len(r.Percentage))
+		for _, variation := range r.sortedPercentageVariations() {
+			split = append(split,
+				fmt.Sprintf("%s=%s%%", variation, strconv.FormatFloat(r.Percentage[variation], 'f', -1, 64)))
+		}
+		toString = append(toString, fmt.Sprintf("percentage=[%s]", strings.Join(split, ", ")))
+	} else {
+		toString = append(toString, fmt.Sprintf("variation=\"%s\"", r.getVariation()))
+	}
+	return strings.Join(toString, ", ")
+}
+
+// getName is the getter of the field Name
+func (r *Rule) getName() string {
+	if r.Name == nil {
+		return ""
+	}
+	return *r.Name
+}
+
+// getQuery is the getter of the field Query
+func (r *Rule) getQuery() string {
+	if r.Query == nil {
+		return ""
+	}
+	return *r.Query
+}
+
+// getVariation is the getter of the field Variation
+func (r *Rule) getVariation() string {
+	if r.Variation == nil {
+		return ""
+	}
+	return *r.Variation
+}
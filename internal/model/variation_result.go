@@ -1,10 +1,30 @@
 package model
 
+import "github.com/thomaspoignant/go-feature-flag/internal/flag"
+
 type VariationResult struct {
 	TrackEvents   bool    `json:"trackEvents"`
 	VariationType string  `json:"variationType"`
 	Failed        bool    `json:"failed"`
 	Version       float64 `json:"version"`
+
+	// Reason is explaining why this variation has been selected.
+	Reason flag.ResolutionReason `json:"reason"`
+
+	// ErrorCode identifies the error if the evaluation has failed.
+	ErrorCode flag.ErrorCode `json:"errorCode,omitempty"`
+
+	// RuleIndex is the index of the targeting rule that applied to the user.
+	RuleIndex *int `json:"ruleIndex,omitempty"`
+
+	// RuleName is the name of the targeting rule that applied to the user.
+	RuleName *string `json:"ruleName,omitempty"`
+
+	// PrerequisiteKey is the key of the prerequisite flag that failed.
+	PrerequisiteKey *string `json:"prerequisiteKey,omitempty"`
+
+	// BucketingKeyFallback is true if the user key has been used because the bucketing key is missing.
+	BucketingKeyFallback bool `json:"bucketingKeyFallback,omitempty"`
 }
 
 // BoolVarResult is the internal result format of a bool variation.
@@ -36,4 +36,23 @@ func (c *LogNotifier) Notify(diff ffnotifier.DiffCache, wg *sync.WaitGroup) {
 		// key has changed in cache
 		fflog.Printf(c.Logger, "flag %s updated, old=[%v], new=[%v]\n", key, flagDiff.Before, flagDiff.After)
 	}
+
+	for name := range diff.DeletedSegments {
+		fflog.Printf(c.Logger, "segment %v removed\n", name)
+	}
+
+	for name := range diff.AddedSegments {
+		fflog.Printf(c.Logger, "segment %v added\n", name)
+	}
+
+	for name, segmentDiff := range diff.UpdatedSegments {
+		fflog.Printf(c.Logger, "segment %s updated, old=[%v], new=[%v]\n", name, segmentDiff.Before, segmentDiff.After)
+	}
+}
+
+// NotifyRejection logs the reason why the update of the flag file has been refused.
+func (c *LogNotifier) NotifyRejection(rejection ffnotifier.UpdateRejection, wg *sync.WaitGroup) {
+	defer wg.Done()
+	fflog.Printf(c.Logger, "error: update of the flag file refused, the current flags are kept: %s\n",
+		rejection.Reason)
 }
@@ -169,3 +169,18 @@ func TestLogNotifier_Notify(t *testing.T) {
 		})
 	}
 }
+
+func TestLogNotifier_NotifyRejection(t *testing.T) {
+	logOutput, _ := ioutil.TempFile("", "")
+	defer os.Remove(logOutput.Name())
+
+	c := &LogNotifier{
+		Logger: log.New(logOutput, "", 0),
+	}
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	c.NotifyRejection(ffnotifier.UpdateRejection{Reason: "the flag file does not contain any flag"}, wg)
+	log, _ := ioutil.ReadFile(logOutput.Name())
+	assert.Regexp(t, "^\\["+testutils.RFC3339Regex+"\\] error: update of the flag file refused, "+
+		"the current flags are kept: the flag file does not contain any flag", string(log))
+}
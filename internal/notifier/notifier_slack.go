@@ -10,12 +10,14 @@ import (
 	"net/url"
 	"os"
 	"sort"
+	"strings"
 	"sync"
 
 	"github.com/thomaspoignant/go-feature-flag/ffnotifier"
 
 	"github.com/thomaspoignant/go-feature-flag/internal"
 	"github.com/thomaspoignant/go-feature-flag/internal/fflog"
+	"github.com/thomaspoignant/go-feature-flag/internal/flag"
 )
 
 const (
@@ -44,9 +46,18 @@ type SlackNotifier struct {
 
 func (c *SlackNotifier) Notify(diff ffnotifier.DiffCache, wg *sync.WaitGroup) {
 	defer wg.Done()
+	c.send(convertToSlackMessage(diff))
+}
+
+// NotifyRejection sends a message explaining why the update of the flag file has been refused.
+func (c *SlackNotifier) NotifyRejection(rejection ffnotifier.UpdateRejection, wg *sync.WaitGroup) {
+	defer wg.Done()
+	c.send(convertRejectionToSlackMessage(rejection))
+}
 
-	reqBody := convertToSlackMessage(diff)
-	payload, err := json.Marshal(reqBody)
+// send posts the message to the slack webhook.
+func (c *SlackNotifier) send(message slackMessage) {
+	payload, err := json.Marshal(message)
 	if err != nil {
 		fflog.Printf(c.Logger, "error: (SlackNotifier) impossible to read differences; %v\n", err)
 		return
@@ -71,11 +82,32 @@ func (c *SlackNotifier) Notify(diff ffnotifier.DiffCache, wg *sync.WaitGroup) {
 	}
 }
 
+func convertRejectionToSlackMessage(rejection ffnotifier.UpdateRejection) slackMessage {
+	hostname, _ := os.Hostname()
+	fields := []Field{{Title: "Reason", Short: len(rejection.Reason) < longSlackAttachment, Value: rejection.Reason}}
+	if len(rejection.DeletedFlags) > 0 {
+		value := strings.Join(rejection.DeletedFlags, ", ")
+		fields = append(fields, Field{Title: "Deleted flags", Short: len(value) < longSlackAttachment, Value: value})
+	}
+	return slackMessage{
+		Text:    fmt.Sprintf("Update of your feature flag file refused on: *%s*", hostname),
+		IconURL: goFFLogo,
+		Attachments: []attachment{{
+			Title:      "⛔ The flags currently served are kept",
+			Color:      colorDeleted,
+			FooterIcon: goFFLogo,
+			Footer:     slackFooter,
+			Fields:     fields,
+		}},
+	}
+}
+
 func convertToSlackMessage(diff ffnotifier.DiffCache) slackMessage {
 	hostname, _ := os.Hostname()
 	attachments := convertDeletedFlagsToSlackMessage(diff)
 	attachments = append(attachments, convertUpdatedFlagsToSlackMessage(diff)...)
 	attachments = append(attachments, convertAddedFlagsToSlackMessage(diff)...)
+	attachments = append(attachments, convertSegmentsToSlackMessage(diff)...)
 	res := slackMessage{
 		Text:        fmt.Sprintf("Changes detected in your feature flag file on: *%s*", hostname),
 		IconURL:     goFFLogo,
@@ -157,6 +189,44 @@ func convertAddedFlagsToSlackMessage(diff ffnotifier.DiffCache) []attachment {
 	return attachments
 }
 
+func convertSegmentsToSlackMessage(diff ffnotifier.DiffCache) []attachment {
+	attachments := make([]attachment, 0)
+	for _, name := range sortedSegmentNames(diff.DeletedSegments) {
+		attachments = append(attachments, attachment{
+			Title:      fmt.Sprintf("❌ Segment \"%s\" deleted", name),
+			Color:      colorDeleted,
+			FooterIcon: goFFLogo,
+			Footer:     slackFooter,
+		})
+	}
+	updatedNames := make([]string, 0, len(diff.UpdatedSegments))
+	for name := range diff.UpdatedSegments {
+		updatedNames = append(updatedNames, name)
+	}
+	sort.Strings(updatedNames)
+	for _, name := range updatedNames {
+		value := fmt.Sprintf("%v => %v", diff.UpdatedSegments[name].Before, diff.UpdatedSegments[name].After)
+		attachments = append(attachments, attachment{
+			Title:      fmt.Sprintf("✏️ Segment \"%s\" updated", name),
+			Color:      colorUpdated,
+			FooterIcon: goFFLogo,
+			Footer:     slackFooter,
+			Fields:     []Field{{Title: "Segment", Short: len(value) < longSlackAttachment, Value: value}},
+		})
+	}
+	for _, name := range sortedSegmentNames(diff.AddedSegments) {
+		value := diff.AddedSegments[name].String()
+		attachments = append(attachments, attachment{
+			Title:      fmt.Sprintf("🆕 Segment \"%s\" created", name),
+			Color:      colorAdded,
+			FooterIcon: goFFLogo,
+			Footer:     slackFooter,
+			Fields:     []Field{{Title: "Segment", Short: len(value) < longSlackAttachment, Value: value}},
+		})
+	}
+	return attachments
+}
+
 type slackMessage struct {
 	IconURL     string       `json:"icon_url"`
 	Text        string       `json:"text"`
@@ -187,3 +257,12 @@ func sortedKeys(m map[string]string) []string {
 	sort.Strings(keys)
 	return keys
 }
+
+func sortedSegmentNames(m map[string]flag.Segment) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
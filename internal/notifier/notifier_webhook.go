@@ -49,11 +49,21 @@ func NewWebhookNotifier(logger *log.Logger,
 	return w, nil
 }
 
+// webhookTypeRejection is the type of the body sent when an update of the flag file is refused,
+// the body of the changes has no type to stay unchanged for the existing receivers.
+const webhookTypeRejection = "rejection"
+
 type webhookReqBody struct {
 	Meta  map[string]string    `json:"meta"`
 	Flags ffnotifier.DiffCache `json:"flags"`
 }
 
+type webhookRejectionReqBody struct {
+	Type      string                     `json:"type"`
+	Meta      map[string]string          `json:"meta"`
+	Rejection ffnotifier.UpdateRejection `json:"rejection"`
+}
+
 type WebhookNotifier struct {
 	Logger      *log.Logger
 	HTTPClient  internal.HTTPClient
@@ -70,7 +80,21 @@ func (c *WebhookNotifier) Notify(diff ffnotifier.DiffCache, wg *sync.WaitGroup)
 		Meta:  c.Meta,
 		Flags: diff,
 	}
+	c.send(reqBody)
+}
+
+// NotifyRejection calls the webhook with the reason why the update of the flag file has been refused.
+func (c *WebhookNotifier) NotifyRejection(rejection ffnotifier.UpdateRejection, wg *sync.WaitGroup) {
+	defer wg.Done()
+	c.send(webhookRejectionReqBody{
+		Type:      webhookTypeRejection,
+		Meta:      c.Meta,
+		Rejection: rejection,
+	})
+}
 
+// send calls the webhook with the body marshalled in JSON.
+func (c *WebhookNotifier) send(reqBody interface{}) {
 	payload, err := json.Marshal(reqBody)
 	if err != nil {
 		fflog.Printf(c.Logger, "error: (WebhookNotifier) impossible to read differences; %v\n", err)
@@ -217,3 +217,27 @@ func TestNewWebhookNotifier(t *testing.T) {
 		})
 	}
 }
+
+func Test_webhookNotifier_NotifyRejection(t *testing.T) {
+	mockHTTPClient := &testutils.HTTPClientMock{StatusCode: http.StatusOK}
+	c, err := NewWebhookNotifier(nil, mockHTTPClient, "http://webhook.example/hook", "test-secret",
+		map[string]string{"hostname": "server01"})
+	assert.NoError(t, err)
+
+	w := sync.WaitGroup{}
+	w.Add(1)
+	c.NotifyRejection(ffnotifier.UpdateRejection{
+		Reason:       "the update deletes 2 of the 3 flags (66.67%), the maximum allowed is 50.00%",
+		DeletedFlags: []string{"flag-a", "flag-b"},
+	}, &w)
+
+	assert.JSONEq(t, `{
+  "type": "rejection",
+  "meta": {"hostname": "server01"},
+  "rejection": {
+    "reason": "the update deletes 2 of the 3 flags (66.67%), the maximum allowed is 50.00%",
+    "deleted_flags": ["flag-a", "flag-b"]
+  }
+}`, mockHTTPClient.Body)
+	assert.NotEmpty(t, mockHTTPClient.Signature)
+}
@@ -0,0 +1,171 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenType int
+
+const (
+	tokenEOF tokenType = iota
+	tokenLeftParen
+	tokenRightParen
+	tokenLeftBracket
+	tokenRightBracket
+	tokenComma
+	tokenString
+	tokenNumber
+	tokenVersion
+	tokenWord
+	tokenOperator
+)
+
+// token is a lexical element of a query.
+type token struct {
+	typ   tokenType
+	value string
+	pos   int
+}
+
+// lex is splitting the query in a list of tokens, the last token is always a tokenEOF.
+func lex(input string) ([]token, error) {
+	tokens := []token{}
+	pos := 0
+	for pos < len(input) {
+		c := input[pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			pos++
+		case c == '(':
+			tokens = append(tokens, token{typ: tokenLeftParen, value: "(", pos: pos})
+			pos++
+		case c == ')':
+			tokens = append(tokens, token{typ: tokenRightParen, value: ")", pos: pos})
+			pos++
+		case c == '[':
+			tokens = append(tokens, token{typ: tokenLeftBracket, value: "[", pos: pos})
+			pos++
+		case c == ']':
+			tokens = append(tokens, token{typ: tokenRightBracket, value: "]", pos: pos})
+			pos++
+		case c == ',':
+			tokens = append(tokens, token{typ: tokenComma, value: ",", pos: pos})
+			pos++
+		case c == '"':
+			tok, err := lexString(input, pos)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, tok)
+			pos += len(tok.value)
+		case isDigit(c) || (c == '-' && pos+1 < len(input) && isDigit(input[pos+1])):
+			tok := lexNumber(input, pos)
+			tokens = append(tokens, tok)
+			pos += len(tok.value)
+		case strings.ContainsRune("=!<>", rune(c)):
+			tok, err := lexOperator(input, pos)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, tok)
+			pos += len(tok.value)
+		case isAttributeStart(c):
+			start := pos
+			for pos < len(input) && isWordChar(input[pos]) {
+				pos++
+			}
+			tokens = append(tokens, token{typ: tokenWord, value: input[start:pos], pos: start})
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", c, pos)
+		}
+	}
+	return append(tokens, token{typ: tokenEOF, pos: pos}), nil
+}
+
+// lexString reads a quoted string, the value of the token contains the quotes.
+func lexString(input string, start int) (token, error) {
+	for pos := start + 1; pos < len(input); pos++ {
+		switch input[pos] {
+		case '\\':
+			pos++
+		case '"':
+			return token{typ: tokenString, value: input[start : pos+1], pos: start}, nil
+		}
+	}
+	return token{}, fmt.Errorf("unterminated string at position %d", start)
+}
+
+// lexNumber reads an int, a double or a version (ex: 1.2.3).
+func lexNumber(input string, start int) token {
+	pos := start
+	if input[pos] == '-' {
+		pos++
+	}
+	pos = skipDigits(input, pos)
+
+	if pos+1 < len(input) && input[pos] == '.' && isDigit(input[pos+1]) {
+		pos = skipDigits(input, pos+1)
+		if input[start] != '-' && pos+1 < len(input) && input[pos] == '.' && isDigit(input[pos+1]) {
+			return token{typ: tokenVersion, value: input[start:skipDigits(input, pos+1)], pos: start}
+		}
+	}
+
+	if pos < len(input) && (input[pos] == 'e' || input[pos] == 'E') {
+		exp := pos + 1
+		if exp < len(input) && (input[exp] == '+' || input[exp] == '-') {
+			exp++
+		}
+		if exp < len(input) && isDigit(input[exp]) {
+			pos = skipDigits(input, exp)
+		}
+	}
+	return token{typ: tokenNumber, value: input[start:pos], pos: start}
+}
+
+// lexOperator reads the symbolic comparison operators (==, !=, <, <=, >, >=).
+func lexOperator(input string, start int) (token, error) {
+	if start+1 < len(input) && input[start+1] == '=' {
+		return token{typ: tokenOperator, value: input[start : start+2], pos: start}, nil
+	}
+	if input[start] == '<' || input[start] == '>' {
+		return token{typ: tokenOperator, value: input[start : start+1], pos: start}, nil
+	}
+	return token{}, fmt.Errorf("unexpected character %q at position %d", input[start], start)
+}
+
+// unquote returns the value of a string token without the quotes.
+func unquote(value string) string {
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		return unquoted
+	}
+	return value[1 : len(value)-1]
+}
+
+func skipDigits(input string, pos int) int {
+	for pos < len(input) && isDigit(input[pos]) {
+		pos++
+	}
+	return pos
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return c < unicode.MaxASCII && unicode.IsLetter(rune(c))
+}
+
+// isAttributeStart returns true for the characters allowed at the start of an attribute name.
+func isAttributeStart(c byte) bool {
+	return isLetter(c) || c == '_'
+}
+
+// isWordChar returns true for the characters allowed in an attribute name,
+// the dot is used to access the nested attributes.
+func isWordChar(c byte) bool {
+	return isLetter(c) || isDigit(c) || c == '-' || c == '_' || c == ':' || c == '.'
+}
@@ -0,0 +1,157 @@
+package query
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// node is an element of the query tree, every node can be evaluated on the attributes of a user.
+type node interface {
+	evaluate(attributes map[string]interface{}, segments SegmentResolver) bool
+	String() string
+}
+
+// logicalNode is a "and" / "or" operation between 2 nodes.
+type logicalNode struct {
+	and   bool
+	left  node
+	right node
+}
+
+func (n *logicalNode) evaluate(attributes map[string]interface{}, segments SegmentResolver) bool {
+	left := n.left.evaluate(attributes, segments)
+	if n.and {
+		return left && n.right.evaluate(attributes, segments)
+	}
+	return left || n.right.evaluate(attributes, segments)
+}
+
+func (n *logicalNode) String() string {
+	if n.and {
+		return fmt.Sprintf("%v and %v", n.left, n.right)
+	}
+	return fmt.Sprintf("%v or %v", n.left, n.right)
+}
+
+// parenNode is an expression between parenthesis, it can be negated.
+type parenNode struct {
+	not  bool
+	expr node
+}
+
+func (n *parenNode) evaluate(attributes map[string]interface{}, segments SegmentResolver) bool {
+	return n.expr.evaluate(attributes, segments) != n.not
+}
+
+func (n *parenNode) String() string {
+	if n.not {
+		return fmt.Sprintf("not (%v)", n.expr)
+	}
+	return fmt.Sprintf("(%v)", n.expr)
+}
+
+// presentNode is checking that an attribute is present.
+type presentNode struct {
+	path []string
+}
+
+func (n *presentNode) evaluate(attributes map[string]interface{}, _ SegmentResolver) bool {
+	return resolvePath(attributes, n.path) != nil
+}
+
+func (n *presentNode) String() string {
+	return fmt.Sprintf("%s pr", strings.Join(n.path, "."))
+}
+
+// compareNode is comparing an attribute of the user with a value of the query.
+type compareNode struct {
+	path     []string
+	operator operator
+	value    value
+}
+
+func (n *compareNode) evaluate(attributes map[string]interface{}, _ SegmentResolver) bool {
+	return n.value.compare(n.operator, resolvePath(attributes, n.path))
+}
+
+func (n *compareNode) String() string {
+	return fmt.Sprintf("%s %s %v", strings.Join(n.path, "."), n.operator, n.value)
+}
+
+// segmentNode is checking if the user is part of a segment.
+type segmentNode struct {
+	name string
+}
+
+func (n *segmentNode) evaluate(attributes map[string]interface{}, segments SegmentResolver) bool {
+	if segments == nil {
+		return false
+	}
+	return segments.IsInSegment(n.name, attributes)
+}
+
+func (n *segmentNode) String() string {
+	return fmt.Sprintf("segment %q", n.name)
+}
+
+// neverNode is the root of a query that cannot be parsed, it never matches.
+type neverNode struct{}
+
+func (n neverNode) evaluate(map[string]interface{}, SegmentResolver) bool {
+	return false
+}
+
+func (n neverNode) String() string {
+	return "never"
+}
+
+// resolvePath returns the value of an attribute, the path is used to access the nested attributes.
+// A part of the path is either the key of a map or the index of a slice (ex: addresses.0.country).
+// It returns nil if the attribute does not exist.
+func resolvePath(attributes map[string]interface{}, path []string) interface{} {
+	var current interface{} = attributes
+	for _, part := range path {
+		switch v := current.(type) {
+		case map[string]interface{}:
+			current = v[part]
+		case []interface{}:
+			index, err := strconv.Atoi(part)
+			if err != nil || index < 0 || index >= len(v) {
+				return nil
+			}
+			current = v[index]
+		default:
+			current = resolveReflect(current, part)
+		}
+		if current == nil {
+			return nil
+		}
+	}
+	return current
+}
+
+// resolveReflect is accessing the element of the other types of maps and slices (ex: map[string]string, []string).
+func resolveReflect(current interface{}, part string) interface{} {
+	value := reflect.ValueOf(current)
+	switch value.Kind() {
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		element := value.MapIndex(reflect.ValueOf(part).Convert(value.Type().Key()))
+		if !element.IsValid() {
+			return nil
+		}
+		return element.Interface()
+	case reflect.Slice, reflect.Array:
+		index, err := strconv.Atoi(part)
+		if err != nil || index < 0 || index >= value.Len() {
+			return nil
+		}
+		return value.Index(index).Interface()
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,377 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/blang/semver"
+)
+
+type operator string
+
+const (
+	operatorEQ operator = "eq"
+	operatorNE operator = "ne"
+	operatorGT operator = "gt"
+	operatorLT operator = "lt"
+	operatorGE operator = "ge"
+	operatorLE operator = "le"
+	operatorCO operator = "co"
+	operatorSW operator = "sw"
+	operatorEW operator = "ew"
+	operatorIN operator = "in"
+)
+
+// operators contains all the ways to write an operator in a query.
+var operators = map[string]operator{
+	"eq": operatorEQ, "==": operatorEQ,
+	"ne": operatorNE, "!=": operatorNE,
+	"gt": operatorGT, ">": operatorGT,
+	"lt": operatorLT, "<": operatorLT,
+	"ge": operatorGE, ">=": operatorGE,
+	"le": operatorLE, "<=": operatorLE,
+	"co": operatorCO,
+	"sw": operatorSW,
+	"ew": operatorEW,
+	"in": operatorIN,
+
+	"before":    operatorBefore,
+	"after":     operatorAfter,
+	"matches":   operatorMatches,
+	"incidr":    operatorInCIDR,
+	"semver_eq": operatorSemverEQ,
+	"semver_ne": operatorSemverNE,
+	"semver_gt": operatorSemverGT,
+	"semver_lt": operatorSemverLT,
+	"semver_ge": operatorSemverGE,
+	"semver_le": operatorSemverLE,
+}
+
+// orderOperators are the operators available for the values that can be ordered.
+var orderOperators = []operator{operatorEQ, operatorNE, operatorGT, operatorLT, operatorGE, operatorLE}
+
+// value is the right side of a comparison, the type of the value decides how the comparison is done.
+type value interface {
+	// supports returns true if the operator can be used with this value.
+	supports(op operator) bool
+	// compare is comparing the attribute of the user with the value.
+	// A missing attribute or an attribute of the wrong type never matches.
+	compare(op operator, attribute interface{}) bool
+	String() string
+}
+
+type stringValue string
+
+func (v stringValue) supports(op operator) bool {
+	return isOrderOperator(op) || op == operatorCO || op == operatorSW || op == operatorEW
+}
+
+func (v stringValue) compare(op operator, attribute interface{}) bool {
+	attr, ok := attribute.(string)
+	if !ok {
+		return false
+	}
+	// string comparisons are case insensitive.
+	l, r := strings.ToLower(attr), strings.ToLower(string(v))
+	switch op {
+	case operatorEQ:
+		return l == r
+	case operatorNE:
+		return l != r
+	case operatorGT:
+		return l > r
+	case operatorLT:
+		return l < r
+	case operatorGE:
+		return l >= r
+	case operatorLE:
+		return l <= r
+	case operatorCO:
+		return strings.Contains(l, r)
+	case operatorSW:
+		return strings.HasPrefix(l, r)
+	case operatorEW:
+		return strings.HasSuffix(l, r)
+	default:
+		return false
+	}
+}
+
+func (v stringValue) String() string {
+	return strconv.Quote(string(v))
+}
+
+type intValue int
+
+func (v intValue) supports(op operator) bool {
+	return isOrderOperator(op)
+}
+
+func (v intValue) compare(op operator, attribute interface{}) bool {
+	attr, ok := asInt(attribute)
+	if !ok {
+		return false
+	}
+	return compareOrdered(op, attr, int(v))
+}
+
+func (v intValue) String() string {
+	return strconv.Itoa(int(v))
+}
+
+type floatValue float64
+
+func (v floatValue) supports(op operator) bool {
+	return isOrderOperator(op)
+}
+
+func (v floatValue) compare(op operator, attribute interface{}) bool {
+	attr, ok := asFloat(attribute)
+	if !ok {
+		return false
+	}
+	return compareOrdered(op, attr, float64(v))
+}
+
+func (v floatValue) String() string {
+	return strconv.FormatFloat(float64(v), 'f', -1, 64)
+}
+
+type boolValue bool
+
+func (v boolValue) supports(op operator) bool {
+	return op == operatorEQ || op == operatorNE
+}
+
+func (v boolValue) compare(op operator, attribute interface{}) bool {
+	attr, ok := attribute.(bool)
+	if !ok {
+		return false
+	}
+	if op == operatorEQ {
+		return attr == bool(v)
+	}
+	return attr != bool(v)
+}
+
+func (v boolValue) String() string {
+	return strconv.FormatBool(bool(v))
+}
+
+type nullValue struct{}
+
+func (v nullValue) supports(op operator) bool {
+	return op == operatorEQ || op == operatorNE
+}
+
+func (v nullValue) compare(op operator, attribute interface{}) bool {
+	if op == operatorEQ {
+		return attribute == nil
+	}
+	return attribute != nil
+}
+
+func (v nullValue) String() string {
+	return "null"
+}
+
+type versionValue struct {
+	version semver.Version
+	raw     string
+}
+
+func (v versionValue) supports(op operator) bool {
+	return isOrderOperator(op)
+}
+
+func (v versionValue) compare(op operator, attribute interface{}) bool {
+	attr, ok := attribute.(string)
+	if !ok {
+		return false
+	}
+	attrVersion, err := semver.Make(attr)
+	if err != nil {
+		return false
+	}
+	return compareOrdered(op, float64(attrVersion.Compare(v.version)), 0)
+}
+
+func (v versionValue) String() string {
+	return v.raw
+}
+
+// intListValue is a list of ints, it can only be used with the "in" operator.
+// Only an attribute of type int can be in the list.
+type intListValue []int
+
+func (v intListValue) supports(op operator) bool {
+	return op == operatorIN
+}
+
+func (v intListValue) compare(_ operator, attribute interface{}) bool {
+	attr, ok := attribute.(int)
+	if !ok {
+		return false
+	}
+	for _, item := range v {
+		if item == attr {
+			return true
+		}
+	}
+	return false
+}
+
+func (v intListValue) String() string {
+	items := make([]string, 0, len(v))
+	for _, item := range v {
+		items = append(items, strconv.Itoa(item))
+	}
+	return fmt.Sprintf("[%s]", strings.Join(items, ", "))
+}
+
+// floatListValue is a list of floats, it can only be used with the "in" operator.
+type floatListValue []float64
+
+func (v floatListValue) supports(op operator) bool {
+	return op == operatorIN
+}
+
+func (v floatListValue) compare(_ operator, attribute interface{}) bool {
+	attr, ok := asFloat(attribute)
+	if !ok {
+		return false
+	}
+	for _, item := range v {
+		if item == attr {
+			return true
+		}
+	}
+	return false
+}
+
+func (v floatListValue) String() string {
+	items := make([]string, 0, len(v))
+	for _, item := range v {
+		items = append(items, strconv.FormatFloat(item, 'f', -1, 64))
+	}
+	return fmt.Sprintf("[%s]", strings.Join(items, ", "))
+}
+
+// stringListValue is a list of strings, the "in" operator is case sensitive for the strings.
+type stringListValue []string
+
+func (v stringListValue) supports(op operator) bool {
+	return op == operatorIN
+}
+
+func (v stringListValue) compare(_ operator, attribute interface{}) bool {
+	attr, ok := attribute.(string)
+	if !ok {
+		return false
+	}
+	for _, item := range v {
+		if item == attr {
+			return true
+		}
+	}
+	return false
+}
+
+func (v stringListValue) String() string {
+	items := make([]string, 0, len(v))
+	for _, item := range v {
+		items = append(items, strconv.Quote(item))
+	}
+	return fmt.Sprintf("[%s]", strings.Join(items, ", "))
+}
+
+func isOrderOperator(op operator) bool {
+	for _, orderOperator := range orderOperators {
+		if op == orderOperator {
+			return true
+		}
+	}
+	return false
+}
+
+func compareOrdered[T int | float64](op operator, l T, r T) bool {
+	switch op {
+	case operatorEQ:
+		return l == r
+	case operatorNE:
+		return l != r
+	case operatorGT:
+		return l > r
+	case operatorLT:
+		return l < r
+	case operatorGE:
+		return l >= r
+	case operatorLE:
+		return l <= r
+	default:
+		return false
+	}
+}
+
+// asInt converts the attribute to be compared with an int value, like the nikunjy/rules library
+// only an int, int32, int64 or float64 (truncated) attribute can be compared with an int.
+func asInt(attribute interface{}) (int, bool) {
+	switch v := attribute.(type) {
+	case int:
+		return v, true
+	case int32:
+		return int(v), true
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), true
+	default:
+		return 0, false
+	}
+}
+
+// asFloat converts the attribute to be compared with a float value, like the nikunjy/rules library
+// only an int or float64 attribute can be compared with a float.
+func asFloat(attribute interface{}) (float64, bool) {
+	switch v := attribute.(type) {
+	case int:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+// toFloat converts any number to a float64, it is used by the extended operators.
+func toFloat(attribute interface{}) (float64, bool) {
+	switch v := attribute.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
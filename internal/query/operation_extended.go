@@ -0,0 +1,246 @@
+package query
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/blang/semver"
+)
+
+// The extended operators are not part of the nikunjy/rules format, the value of the query is
+// converted to the type expected by the operator when the query is parsed.
+const (
+	operatorBefore   operator = "before"
+	operatorAfter    operator = "after"
+	operatorMatches  operator = "matches"
+	operatorInCIDR   operator = "incidr"
+	operatorSemverEQ operator = "semver_eq"
+	operatorSemverNE operator = "semver_ne"
+	operatorSemverGT operator = "semver_gt"
+	operatorSemverLT operator = "semver_lt"
+	operatorSemverGE operator = "semver_ge"
+	operatorSemverLE operator = "semver_le"
+)
+
+// semverOperators are the semantic version operators and the order operator used to compare the versions.
+var semverOperators = map[operator]operator{
+	operatorSemverEQ: operatorEQ,
+	operatorSemverNE: operatorNE,
+	operatorSemverGT: operatorGT,
+	operatorSemverLT: operatorLT,
+	operatorSemverGE: operatorGE,
+	operatorSemverLE: operatorLE,
+}
+
+// dateLayouts are the formats accepted for the dates, in the query and in the attributes.
+var dateLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"}
+
+// typedValue converts the value of the query to the type expected by the extended operators.
+// The value is returned unchanged if it cannot be converted, the operator is then rejected by supports.
+func typedValue(op operator, val value) (value, error) {
+	switch op {
+	case operatorBefore, operatorAfter:
+		if raw, ok := val.(stringValue); ok {
+			date, ok := parseDate(string(raw))
+			if !ok {
+				return nil, fmt.Errorf("invalid date %s, expected format is RFC3339 or YYYY-MM-DD", raw)
+			}
+			return dateValue{date: date, raw: string(raw)}, nil
+		}
+	case operatorMatches:
+		if raw, ok := val.(stringValue); ok {
+			re, err := regexp.Compile(string(raw))
+			if err != nil {
+				return nil, fmt.Errorf("invalid regular expression %s: %v", raw, err)
+			}
+			return regexValue{re: re}, nil
+		}
+	case operatorInCIDR:
+		return newCIDRValue(val)
+	case operatorSemverEQ, operatorSemverNE, operatorSemverGT, operatorSemverLT, operatorSemverGE, operatorSemverLE:
+		var raw string
+		switch v := val.(type) {
+		case stringValue:
+			raw = string(v)
+		case versionValue:
+			raw = v.raw
+		default:
+			return val, nil
+		}
+		version, err := semver.ParseTolerant(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid semantic version %s: %v", raw, err)
+		}
+		return semverValue{version: version, raw: raw}, nil
+	}
+	return val, nil
+}
+
+// dateValue is a date used with the operators before and after.
+// The attribute can be a time.Time, a string using one of the dateLayouts or a unix timestamp in seconds.
+type dateValue struct {
+	date time.Time
+	raw  string
+}
+
+func (v dateValue) supports(op operator) bool {
+	return op == operatorBefore || op == operatorAfter
+}
+
+func (v dateValue) compare(op operator, attribute interface{}) bool {
+	var date time.Time
+	switch attr := attribute.(type) {
+	case time.Time:
+		date = attr
+	case string:
+		parsed, ok := parseDate(attr)
+		if !ok {
+			return false
+		}
+		date = parsed
+	default:
+		timestamp, ok := toFloat(attribute)
+		if !ok {
+			return false
+		}
+		date = time.Unix(int64(timestamp), 0)
+	}
+
+	if op == operatorBefore {
+		return date.Before(v.date)
+	}
+	return date.After(v.date)
+}
+
+func (v dateValue) String() string {
+	return strconv.Quote(v.raw)
+}
+
+// regexValue is a regular expression used with the operator matches, the match is case sensitive.
+type regexValue struct {
+	re *regexp.Regexp
+}
+
+func (v regexValue) supports(op operator) bool {
+	return op == operatorMatches
+}
+
+func (v regexValue) compare(_ operator, attribute interface{}) bool {
+	attr, ok := attribute.(string)
+	return ok && v.re.MatchString(attr)
+}
+
+func (v regexValue) String() string {
+	return strconv.Quote(v.re.String())
+}
+
+// cidrValue is a list of networks used with the operator incidr, a single IP is a network
+// containing only this IP.
+type cidrValue struct {
+	networks []*net.IPNet
+	raw      []string
+}
+
+func newCIDRValue(val value) (value, error) {
+	var raws []string
+	switch v := val.(type) {
+	case stringValue:
+		raws = []string{string(v)}
+	case stringListValue:
+		raws = v
+	default:
+		return val, nil
+	}
+
+	cidr := cidrValue{raw: raws}
+	for _, raw := range raws {
+		if !strings.Contains(raw, "/") {
+			ip := net.ParseIP(raw)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP %q", raw)
+			}
+			bits := 8 * len(ip)
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			cidr.networks = append(cidr.networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %v", raw, err)
+		}
+		cidr.networks = append(cidr.networks, network)
+	}
+	return cidr, nil
+}
+
+func (v cidrValue) supports(op operator) bool {
+	return op == operatorInCIDR
+}
+
+func (v cidrValue) compare(_ operator, attribute interface{}) bool {
+	attr, ok := attribute.(string)
+	if !ok {
+		return false
+	}
+	ip := net.ParseIP(attr)
+	if ip == nil {
+		return false
+	}
+	for _, network := range v.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (v cidrValue) String() string {
+	if len(v.raw) == 1 {
+		return strconv.Quote(v.raw[0])
+	}
+	return stringListValue(v.raw).String()
+}
+
+// semverValue is a semantic version used with the semver operators.
+// Contrary to the unquoted versions, it supports the pre-release versions (ex: "1.2.0-beta.1") and the "v" prefix.
+type semverValue struct {
+	version semver.Version
+	raw     string
+}
+
+func (v semverValue) supports(op operator) bool {
+	_, ok := semverOperators[op]
+	return ok
+}
+
+func (v semverValue) compare(op operator, attribute interface{}) bool {
+	attr, ok := attribute.(string)
+	if !ok {
+		return false
+	}
+	attrVersion, err := semver.ParseTolerant(attr)
+	if err != nil {
+		return false
+	}
+	return compareOrdered(semverOperators[op], float64(attrVersion.Compare(v.version)), 0)
+}
+
+func (v semverValue) String() string {
+	return strconv.Quote(v.raw)
+}
+
+// parseDate reads a date using one of the dateLayouts.
+func parseDate(raw string) (time.Time, bool) {
+	for _, layout := range dateLayouts {
+		if date, err := time.Parse(layout, raw); err == nil {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,118 @@
+package query_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thomaspoignant/go-feature-flag/internal/query"
+)
+
+func TestQuery_extendedOperators(t *testing.T) {
+	attributes := map[string]interface{}{
+		"key":          "user-key",
+		"appVersion":   "2.3.0-beta.1",
+		"osVersion":    "v14.1",
+		"signupDate":   "2022-03-15T10:00:00Z",
+		"birthday":     "1990-05-01",
+		"lastLogin":    time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC),
+		"createdAt":    1640995200, // 2022-01-01T00:00:00Z
+		"email":        "john.doe@example.com",
+		"ip":           "192.168.1.12",
+		"ipv6":         "2001:db8::1",
+		"invalidDate":  "yesterday",
+		"invalidIP":    "999.1.1.1",
+		"numberAttr":   12,
+		"semverNumber": 2,
+	}
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{query: `appVersion semver_gt "2.2.0"`, want: true},
+		{query: `appVersion semver_lt "2.3.0"`, want: true},
+		{query: `appVersion semver_ge 2.3.0`, want: false},
+		{query: `appVersion semver_eq "2.3.0-beta.1"`, want: true},
+		{query: `appVersion SEMVER_NE "2.3.0-beta.1"`, want: false},
+		{query: `osVersion semver_le "14.1.0"`, want: true},
+		{query: `email semver_gt "1.0.0"`, want: false},
+		{query: `semverNumber semver_gt "1.0.0"`, want: false},
+		{query: `signupDate after "2022-01-01"`, want: true},
+		{query: `signupDate before "2022-03-15T11:30:00+01:00"`, want: true},
+		{query: `birthday BEFORE "2000-01-01"`, want: true},
+		{query: `lastLogin after "2022-05-31T23:59:59Z"`, want: true},
+		{query: `createdAt before "2022-01-01T00:00:01Z"`, want: true},
+		{query: `createdAt after "2022-01-01"`, want: false},
+		{query: `invalidDate after "2022-01-01"`, want: false},
+		{query: `unknown after "2022-01-01"`, want: false},
+		{query: `email matches "^[a-z.]+@example\\.com$"`, want: true},
+		{query: `email matches "^JOHN"`, want: false},
+		{query: `email matches "(?i)^JOHN"`, want: true},
+		{query: `numberAttr matches "12"`, want: false},
+		{query: `ip incidr "192.168.0.0/16"`, want: true},
+		{query: `ip incidr "10.0.0.0/8"`, want: false},
+		{query: `ip incidr ["10.0.0.0/8", "192.168.1.0/24"]`, want: true},
+		{query: `ip incidr "192.168.1.12"`, want: true},
+		{query: `ipv6 incidr "2001:db8::/32"`, want: true},
+		{query: `ipv6 incidr "192.168.0.0/16"`, want: false},
+		{query: `invalidIP incidr "0.0.0.0/0"`, want: false},
+		{query: `ip incidr "10.0.0.0/8" or appVersion semver_ge "2.0.0"`, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := query.Parse(tt.query)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, q.Evaluate(attributes, nil))
+		})
+	}
+}
+
+func TestParse_invalidExtendedOperators(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantErr string
+	}{
+		{
+			query:   `signupDate after "01/02/2022"`,
+			wantErr: `invalid date "01/02/2022", expected format is RFC3339 or YYYY-MM-DD at position 17`,
+		},
+		{
+			query:   `signupDate after 2022`,
+			wantErr: `operator "after" cannot be used with the value 2022 at position 17`,
+		},
+		{
+			query:   `email matches "[a-z"`,
+			wantErr: "invalid regular expression \"[a-z\": error parsing regexp: missing closing ]: `[a-z` at position 14",
+		},
+		{
+			query:   `ip incidr "192.168.0.0/33"`,
+			wantErr: `invalid CIDR "192.168.0.0/33": invalid CIDR address: 192.168.0.0/33 at position 10`,
+		},
+		{
+			query:   `ip incidr "not-an-ip"`,
+			wantErr: `invalid IP "not-an-ip" at position 10`,
+		},
+		{
+			query:   `appVersion semver_gt "latest"`,
+			wantErr: `invalid semantic version latest: Invalid character(s) found in major number "latest" at position 21`,
+		},
+		{
+			query:   `appVersion semver_gt true`,
+			wantErr: `operator "semver_gt" cannot be used with the value true at position 21`,
+		},
+		{
+			query:   `email co ["a"]`,
+			wantErr: `operator "co" cannot be used with the value ["a"] at position 9`,
+		},
+		{
+			query:   `email eq "a" and ip matches 12`,
+			wantErr: `operator "matches" cannot be used with the value 12 at position 28`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := query.Parse(tt.query)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
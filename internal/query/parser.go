@@ -0,0 +1,267 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/blang/semver"
+)
+
+// parser is building the query tree from the tokens.
+//
+// The grammar follows the nikunjy/rules format, the values are compared like in nikunjy/rules
+// (see TestCompatibility) but an invalid query is reported when it is parsed.
+// The segment operator, the nested attribute paths and the extended operators are not part of nikunjy/rules:
+//
+//	query      := unary (("and" | "or") unary)*
+//	unary      := "not"? "(" query ")" | "not"? segment | comparison
+//	segment    := "segment" STRING
+//	comparison := attrPath "pr" | attrPath operator value
+//
+// The "and" and "or" operators have the same precedence and are evaluated from left to right.
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) peekNext() token {
+	if p.pos+1 < len(p.tokens) {
+		return p.tokens[p.pos+1]
+	}
+	return p.tokens[len(p.tokens)-1]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.typ != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) parseQuery() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for isKeyword(p.peek(), "and") || isKeyword(p.peek(), "or") {
+		and := isKeyword(p.next(), "and")
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{and: and, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	not := false
+	if isKeyword(p.peek(), "not") {
+		p.next()
+		not = true
+	}
+
+	switch tok := p.peek(); {
+	case tok.typ == tokenLeftParen:
+		p.next()
+		expr, err := p.parseQuery()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.typ != tokenRightParen {
+			return nil, unexpectedToken(closing, "\")\"")
+		}
+		return &parenNode{not: not, expr: expr}, nil
+
+	case isKeyword(tok, "segment") && p.peekNext().typ == tokenString:
+		p.next()
+		var expr node = &segmentNode{name: unquote(p.next().value)}
+		if not {
+			expr = &parenNode{not: true, expr: expr}
+		}
+		return expr, nil
+
+	case not:
+		return nil, unexpectedToken(tok, "\"(\" or a segment after \"not\"")
+
+	default:
+		return p.parseComparison()
+	}
+}
+
+func (p *parser) parseComparison() (node, error) {
+	attr := p.next()
+	if attr.typ != tokenWord {
+		return nil, unexpectedToken(attr, "an attribute name")
+	}
+	path := strings.Split(attr.value, ".")
+	for index, part := range path {
+		// the nested parts of the path can be the index of a slice.
+		if part == "" || (!isAttributeStart(part[0]) && (index == 0 || !isIndex(part))) {
+			return nil, fmt.Errorf("invalid attribute name %q at position %d", attr.value, attr.pos)
+		}
+	}
+
+	opToken := p.next()
+	if isKeyword(opToken, "pr") {
+		return &presentNode{path: path}, nil
+	}
+	name := opToken.value
+	if opToken.typ == tokenWord && name == strings.ToUpper(name) {
+		name = strings.ToLower(name)
+	}
+	op, ok := operators[name]
+	if !ok || (opToken.typ != tokenWord && opToken.typ != tokenOperator) {
+		return nil, unexpectedToken(opToken, "an operator")
+	}
+
+	valueToken := p.peek()
+	val, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	if val, err = typedValue(op, val); err != nil {
+		return nil, fmt.Errorf("%v at position %d", err, valueToken.pos)
+	}
+	if !val.supports(op) {
+		return nil, fmt.Errorf("operator %q cannot be used with the value %v at position %d",
+			opToken.value, val, valueToken.pos)
+	}
+	return &compareNode{path: path, operator: op, value: val}, nil
+}
+
+func (p *parser) parseValue() (value, error) {
+	tok := p.next()
+	switch tok.typ {
+	case tokenString:
+		return stringValue(unquote(tok.value)), nil
+	case tokenNumber:
+		return parseNumber(tok)
+	case tokenVersion:
+		version, err := semver.Make(tok.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version %s at position %d: %v", tok.value, tok.pos, err)
+		}
+		return versionValue{version: version, raw: tok.value}, nil
+	case tokenLeftBracket:
+		return p.parseList()
+	case tokenWord:
+		switch strings.ToLower(tok.value) {
+		case "true":
+			return boolValue(true), nil
+		case "false":
+			return boolValue(false), nil
+		case "null":
+			return nullValue{}, nil
+		}
+	}
+	return nil, unexpectedToken(tok, "a value")
+}
+
+// parseList reads a list of strings, a list of ints or a list of floats, a list cannot mix them.
+// An empty list is allowed, it never contains the attribute.
+func (p *parser) parseList() (value, error) {
+	strs := stringListValue{}
+	ints := intListValue{}
+	floats := floatListValue{}
+	if p.peek().typ == tokenRightBracket {
+		p.next()
+		return ints, nil
+	}
+	for {
+		tok := p.next()
+		switch tok.typ {
+		case tokenString:
+			strs = append(strs, unquote(tok.value))
+		case tokenNumber:
+			number, err := parseNumber(tok)
+			if err != nil {
+				return nil, err
+			}
+			if i, ok := number.(intValue); ok {
+				ints = append(ints, int(i))
+			} else {
+				floats = append(floats, float64(number.(floatValue)))
+			}
+		default:
+			return nil, unexpectedToken(tok, "a string or a number")
+		}
+		if countNonEmpty(len(strs), len(ints), len(floats)) > 1 {
+			return nil, fmt.Errorf("a list cannot mix strings, ints and floats at position %d", tok.pos)
+		}
+
+		separator := p.next()
+		if separator.typ == tokenRightBracket {
+			break
+		}
+		if separator.typ != tokenComma {
+			return nil, unexpectedToken(separator, "\",\" or \"]\"")
+		}
+	}
+
+	switch {
+	case len(strs) > 0:
+		return strs, nil
+	case len(floats) > 0:
+		return floats, nil
+	default:
+		return ints, nil
+	}
+}
+
+// parseNumber returns an intValue or a floatValue depending on the format of the number,
+// like in the nikunjy/rules format a float must contain a "." (ex: 30.0 or 1.5e3).
+func parseNumber(tok token) (value, error) {
+	if !strings.Contains(tok.value, ".") {
+		i, err := strconv.Atoi(tok.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid int %s at position %d", tok.value, tok.pos)
+		}
+		return intValue(i), nil
+	}
+	f, err := strconv.ParseFloat(tok.value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %s at position %d", tok.value, tok.pos)
+	}
+	return floatValue(f), nil
+}
+
+// countNonEmpty returns the number of lengths that are not 0.
+func countNonEmpty(lengths ...int) int {
+	count := 0
+	for _, length := range lengths {
+		if length > 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// isIndex returns true if the part of an attribute path is the index of a slice.
+func isIndex(part string) bool {
+	for i := 0; i < len(part); i++ {
+		if !isDigit(part[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isKeyword checks if the token is the keyword (keywords can be lower case or upper case).
+func isKeyword(tok token, keyword string) bool {
+	return tok.typ == tokenWord && (tok.value == keyword || tok.value == strings.ToUpper(keyword))
+}
+
+func unexpectedToken(tok token, expected string) error {
+	if tok.typ == tokenEOF {
+		return fmt.Errorf("unexpected end of the query, expecting %s", expected)
+	}
+	return fmt.Errorf("unexpected %q at position %d, expecting %s", tok.value, tok.pos, expected)
+}
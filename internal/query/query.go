@@ -0,0 +1,77 @@
+package query
+
+// SegmentResolver is used by the segment operator to check if a user is part of a segment.
+type SegmentResolver interface {
+	// IsInSegment returns true if the user represented by his attributes is part of the segment.
+	// An unknown segment never contains the user.
+	IsInSegment(name string, attributes map[string]interface{}) bool
+}
+
+// Query is a parsed query, it can be evaluated several times on different users.
+// A Query is immutable and safe for concurrent use.
+type Query struct {
+	raw  string
+	root node
+}
+
+// Parse is reading the query and returns an error if the query is invalid.
+func Parse(raw string) (*Query, error) {
+	tokens, err := lex(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseQuery()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.next(); tok.typ != tokenEOF {
+		return nil, unexpectedToken(tok, "\"and\" or \"or\"")
+	}
+	return &Query{raw: raw, root: root}, nil
+}
+
+// Never returns a query that never matches, it replaces a query that cannot be parsed
+// so the query is evaluated like an invalid query of the nikunjy/rules library.
+func Never(raw string) *Query {
+	return &Query{raw: raw, root: neverNode{}}
+}
+
+// Evaluate returns true if the user represented by his attributes matches the query.
+func (q *Query) Evaluate(attributes map[string]interface{}, segments SegmentResolver) bool {
+	return q.root.evaluate(attributes, segments)
+}
+
+// Segments returns the names of the segments used in the query.
+func (q *Query) Segments() []string {
+	return collectSegments(q.root, []string{})
+}
+
+// String returns the query as it was written.
+func (q *Query) String() string {
+	return q.raw
+}
+
+// Evaluate is parsing and evaluating the query in one call, an invalid query never matches.
+func Evaluate(raw string, attributes map[string]interface{}, segments SegmentResolver) bool {
+	q, err := Parse(raw)
+	if err != nil {
+		return false
+	}
+	return q.Evaluate(attributes, segments)
+}
+
+// collectSegments is walking the query tree to find the segment nodes.
+func collectSegments(n node, segments []string) []string {
+	switch v := n.(type) {
+	case *segmentNode:
+		return append(segments, v.name)
+	case *parenNode:
+		return collectSegments(v.expr, segments)
+	case *logicalNode:
+		return collectSegments(v.right, collectSegments(v.left, segments))
+	default:
+		return segments
+	}
+}
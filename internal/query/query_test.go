@@ -0,0 +1,282 @@
+package query_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thomaspoignant/go-feature-flag/internal/query"
+)
+
+type segmentsMock map[string][]string
+
+func (s segmentsMock) IsInSegment(name string, attributes map[string]interface{}) bool {
+	for _, key := range s[name] {
+		if key == attributes["key"] {
+			return true
+		}
+	}
+	return false
+}
+
+var testAttributes = map[string]interface{}{
+	"key":       "user-key",
+	"_key":      "internal-key",
+	"anonymous": false,
+	"email":     "John.Doe@Example.com",
+	"age":       30,
+	"score":     12.5,
+	"beta":      true,
+	"version":   "1.2.3",
+	// the numbers decoded from JSON are float64, the other types come from the custom attributes.
+	"agef":  float64(30),
+	"ratio": 30.7,
+	"ageu":  uint(30),
+	"age8":  int8(30),
+	"age32": int32(30),
+	"age64": int64(30),
+	"f32":   float32(1.5),
+	"company": map[string]interface{}{
+		"name": "go-feature-flag",
+		"size": 42,
+	},
+}
+
+// TestCompatibility is checking that the queries are evaluated like the nikunjy/rules library,
+// the expected results are the ones returned by nikunjy/rules v0.0.0-20200120082459-0b7c4dc9dc86.
+func TestCompatibility(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{query: `key eq "user-key"`, want: true},
+		{query: `key == "USER-KEY"`, want: true},
+		{query: `key ne "user-key"`, want: false},
+		{query: `key != "other"`, want: true},
+		{query: `email ew "@example.com"`, want: true},
+		{query: `email sw "john"`, want: true},
+		{query: `email co "doe"`, want: true},
+		{query: `email co "jane"`, want: false},
+		{query: `email gt "a"`, want: true},
+		{query: `email lt "a"`, want: false},
+		{query: `email ge "john.doe@example.com"`, want: true},
+		{query: `email le "john"`, want: false},
+		{query: `key in ["user-key", "other"]`, want: true},
+		{query: `key in ["USER-KEY"]`, want: false},
+		{query: `age eq 30`, want: true},
+		{query: `age > 18`, want: true},
+		{query: `age < 18`, want: false},
+		{query: `age >= 30`, want: true},
+		{query: `age <= 29`, want: false},
+		{query: `age in [10, 20, 30]`, want: true},
+		{query: `key in []`, want: false},
+		{query: `age in []`, want: false},
+		{query: `score gt 12.4`, want: true},
+		{query: `score eq 12.5`, want: true},
+		{query: `score lt 10.0`, want: false},
+		{query: `score in [12.5, 13.5]`, want: true},
+		{query: `beta eq true`, want: true},
+		{query: `beta ne true`, want: false},
+		{query: `anonymous eq false`, want: true},
+		{query: `missing eq "value"`, want: false},
+		{query: `missing ne "value"`, want: false},
+		{query: `missing eq null`, want: true},
+		{query: `missing ne null`, want: false},
+		{query: `key eq null`, want: false},
+		{query: `key pr`, want: true},
+		{query: `missing pr`, want: false},
+		{query: `version gt 1.2.0`, want: true},
+		{query: `version eq 1.2.3`, want: true},
+		{query: `version lt 1.10.0`, want: true},
+		{query: `company.name eq "go-feature-flag"`, want: true},
+		{query: `company.size gt 40`, want: true},
+		{query: `company.missing pr`, want: false},
+		{query: `_key pr`, want: true},
+		{query: `_missing pr`, want: false},
+		{query: `key eq "user-key" and age gt 18`, want: true},
+		{query: `key eq "user-key" and age lt 18`, want: false},
+		{query: `key eq "other" or age gt 18`, want: true},
+		{query: `key eq "other" or age lt 18`, want: false},
+		{query: `key eq "other" or age gt 18 and beta eq false`, want: false},
+		{query: `key eq "user-key" and age lt 18 or beta eq true`, want: true},
+		{query: `(key eq "other" or age gt 18) and (beta eq true)`, want: true},
+		{query: `not (key eq "user-key")`, want: false},
+		{query: `not (key eq "other") and age gt 18`, want: true},
+		{query: `key eq "user-key" and (age lt 18 or (beta eq true and email ew "example.com"))`, want: true},
+		{query: `age eq "30"`, want: false},
+		{query: `key eq 1`, want: false},
+		{query: `beta eq "true"`, want: false},
+		{query: `agef eq 30`, want: true},
+		{query: `agef eq 30.0`, want: true},
+		{query: `agef gt 29`, want: true},
+		{query: `agef in [30]`, want: false},
+		{query: `agef in [30.0]`, want: true},
+		{query: `ratio eq 30`, want: true},
+		{query: `ratio gt 30`, want: false},
+		{query: `ratio in [30]`, want: false},
+		{query: `ageu eq 30`, want: false},
+		{query: `ageu gt 1`, want: false},
+		{query: `ageu in [30]`, want: false},
+		{query: `ageu eq 30.0`, want: false},
+		{query: `age8 eq 30`, want: false},
+		{query: `age32 eq 30`, want: true},
+		{query: `age32 eq 30.0`, want: false},
+		{query: `age32 in [30]`, want: false},
+		{query: `age32 in [30.0]`, want: false},
+		{query: `age64 eq 30`, want: true},
+		{query: `age64 ge 30`, want: true},
+		{query: `age64 eq 30.0`, want: false},
+		{query: `age64 in [30]`, want: false},
+		{query: `f32 eq 1.5`, want: false},
+		{query: `f32 gt 1.0`, want: false},
+		{query: `f32 eq 1`, want: false},
+		{query: `f32 in [1.5]`, want: false},
+		{query: `age eq 30.0`, want: true},
+		{query: `age in [30.0]`, want: true},
+		{query: `age gt 29.5`, want: true},
+		{query: `age eq -30`, want: false},
+		{query: `age gt -1`, want: true},
+		{query: `score eq 12`, want: true},
+		{query: `score in [12]`, want: false},
+		{query: `score gt 1.25e1`, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			parsed, err := query.Parse(tt.query)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, parsed.Evaluate(testAttributes, nil))
+		})
+	}
+}
+
+func TestParse_invalidQuery(t *testing.T) {
+	queries := []string{
+		``,
+		`key`,
+		`key eq`,
+		`key equals "user-key"`,
+		`key eq "user-key`,
+		`key eq "user-key" and`,
+		`key eq "user-key" xor age eq 1`,
+		`(key eq "user-key"`,
+		`key eq "user-key")`,
+		`age co 12`,
+		`beta gt true`,
+		`key in "user-key"`,
+		`key eq ["user-key"]`,
+		`key in ["a", 1]`,
+		`not key eq "user-key"`,
+		`key = "user-key"`,
+		`key eq user-key`,
+		`.key eq "user-key"`,
+		`key eq "a" # comment`,
+		`age lt 1e3`,
+		`age in [1, 2.5]`,
+		`age in [1, "a"]`,
+	}
+	for _, q := range queries {
+		t.Run(q, func(t *testing.T) {
+			_, err := query.Parse(q)
+			assert.Error(t, err)
+			assert.False(t, query.Evaluate(q, testAttributes, nil))
+		})
+	}
+}
+
+func TestQuery_Evaluate(t *testing.T) {
+	segments := segmentsMock{"beta-customers": {"user-key", "other-key"}}
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{query: `segment "beta-customers"`, want: true},
+		{query: `segment "unknown-segment"`, want: false},
+		{query: `not segment "beta-customers"`, want: false},
+		{query: `NOT (segment "beta-customers")`, want: false},
+		{query: `segment "beta-customers" and age gt 40`, want: false},
+		{query: `segment "unknown-segment" or age gt 18`, want: true},
+		{query: `segment eq "attribute named segment"`, want: false},
+		{query: `key EQ "user-key" AND age GT 18`, want: true},
+		{query: "key eq \"user-key\"\n  and age gt 18", want: true},
+		{query: `key  eq  "user-key"`, want: true},
+		{query: `email eq "john.doe@example.com"`, want: true},
+		{query: `age eq 30.0`, want: true},
+		{query: `age in [30]`, want: true},
+		{query: `age in []`, want: false},
+		{query: `not (key in [])`, want: true},
+		{query: `_key eq "internal-key"`, want: true},
+		{query: `_key eq "other"`, want: false},
+		{query: `score in [12]`, want: false},
+		{query: `key eq "user\"key"`, want: false},
+		{query: `company.name.first eq "go"`, want: false},
+		{query: `age gt -1`, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := query.Parse(tt.query)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, q.Evaluate(testAttributes, segments))
+			assert.Equal(t, tt.query, q.String())
+		})
+	}
+}
+
+func TestQuery_Segments(t *testing.T) {
+	q, err := query.Parse(`segment "a" and (key eq "user-key" or not segment "b")`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, q.Segments())
+
+	q, err = query.Parse(`key eq "user-key"`)
+	assert.NoError(t, err)
+	assert.Empty(t, q.Segments())
+}
+
+func TestQuery_segmentWithoutResolver(t *testing.T) {
+	assert.False(t, query.Evaluate(`segment "beta-customers"`, testAttributes, nil))
+}
+
+func TestQuery_nestedAttributes(t *testing.T) {
+	attributes := map[string]interface{}{
+		"address": map[string]interface{}{
+			"country": "france",
+			"geo":     map[string]interface{}{"lat": 48.85},
+		},
+		"addresses": []interface{}{
+			map[string]interface{}{"country": "france"},
+			map[string]interface{}{"country": "spain"},
+		},
+		"labels": map[string]string{"team": "core"},
+		"roles":  []string{"admin", "dev"},
+		"scores": map[string]interface{}{"2022": 12},
+	}
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{query: `address.country eq "france"`, want: true},
+		{query: `address.geo.lat gt 48.5`, want: true},
+		{query: `address.city pr`, want: false},
+		{query: `addresses.1.country eq "spain"`, want: true},
+		{query: `addresses.2.country eq "spain"`, want: false},
+		{query: `addresses.country eq "spain"`, want: false},
+		{query: `labels.team eq "core"`, want: true},
+		{query: `labels.unknown pr`, want: false},
+		{query: `roles.0 eq "admin"`, want: true},
+		{query: `roles.1 in ["dev", "ops"]`, want: true},
+		{query: `roles.5 pr`, want: false},
+		{query: `scores.2022 eq 12`, want: true},
+		{query: `address.country.name eq "france"`, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := query.Parse(tt.query)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, q.Evaluate(attributes, nil))
+		})
+	}
+
+	for _, invalid := range []string{`0.country eq "france"`, `addresses.1a eq "a"`, `addresses..country eq "a"`} {
+		_, err := query.Parse(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"fmt"
+
+	"github.com/thomaspoignant/go-feature-flag/ffuser"
+)
+
+// BucketingKey returns the value used to bucket the user in a percentage.
+// If no attribute is configured we use the user key, if the attribute is configured but
+// missing from the user custom fields, we fall back to the user key and fallback is true.
+func BucketingKey(user ffuser.User, attribute string) (key string, fallback bool) {
+	if attribute == "" {
+		return user.GetKey(), false
+	}
+	value, ok := user.GetCustom()[attribute]
+	if !ok || value == nil {
+		return user.GetKey(), true
+	}
+	return fmt.Sprintf("%v", value), false
+}
+
+// MaxBucket is the number of buckets available to split the users in the percentages.
+const MaxBucket = uint32(100000)
+
+// Bucket returns the bucket of a user for a flag, the value is between 0 and MaxBucket-1.
+// The hash input is the flag name followed by the bucketing key, if a seed is set on the flag
+// it replaces the flag name, so renaming the flag does not reshuffle the users.
+func Bucket(flagName string, seed string, bucketingKey string) uint32 {
+	if seed != "" {
+		return Hash(seed+bucketingKey) % MaxBucket
+	}
+	return Hash(flagName+bucketingKey) % MaxBucket
+}
@@ -0,0 +1,32 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thomaspoignant/go-feature-flag/ffuser"
+	"github.com/thomaspoignant/go-feature-flag/internal/utils"
+)
+
+func TestBucketingKey(t *testing.T) {
+	user := ffuser.NewUserBuilder("user-key").AddCustom("companyId", 42).Build()
+
+	key, fallback := utils.BucketingKey(user, "")
+	assert.Equal(t, "user-key", key)
+	assert.False(t, fallback)
+
+	key, fallback = utils.BucketingKey(user, "companyId")
+	assert.Equal(t, "42", key)
+	assert.False(t, fallback)
+
+	key, fallback = utils.BucketingKey(user, "deviceId")
+	assert.Equal(t, "user-key", key)
+	assert.True(t, fallback)
+}
+
+func TestBucket(t *testing.T) {
+	assert.Equal(t, utils.Hash("flag-nameuser-key")%utils.MaxBucket, utils.Bucket("flag-name", "", "user-key"))
+	assert.Equal(t, utils.Hash("seeduser-key")%utils.MaxBucket, utils.Bucket("flag-name", "seed", "user-key"))
+	assert.Equal(t, utils.Bucket("flag-1", "seed", "user-key"), utils.Bucket("flag-2", "seed", "user-key"))
+	assert.Less(t, utils.Bucket("flag-name", "", "user-key"), utils.MaxBucket)
+}
@@ -0,0 +1,71 @@
+package ffclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/thomaspoignant/go-feature-flag/internal/cache"
+	"github.com/thomaspoignant/go-feature-flag/internal/fflog"
+)
+
+// persistedFlags is the content of the PersistentFlagConfigurationFile,
+// it is the raw flag configuration as returned by the retriever.
+type persistedFlags struct {
+	Format    string    `json:"format"`
+	Timestamp time.Time `json:"timestamp"`
+	Content   string    `json:"content"`
+}
+
+// persistFlags writes the raw flag configuration in the PersistentFlagConfigurationFile.
+// The file is written in a temporary file first and renamed, so a crash never leaves a partial file.
+func persistFlags(path string, content []byte, format string) error {
+	data, err := json.Marshal(persistedFlags{
+		Format:    format,
+		Timestamp: time.Now(),
+		Content:   string(content),
+	})
+	if err != nil {
+		return err
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmpFile.Name()) }()
+
+	if _, err = tmpFile.Write(data); err != nil {
+		_ = tmpFile.Close()
+		return err
+	}
+	if err = tmpFile.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile.Name(), path)
+}
+
+// loadPersistedFlags updates the cache with the flag configuration stored in the PersistentFlagConfigurationFile.
+func loadPersistedFlags(config Config, flagCache cache.Manager) error {
+	data, err := os.ReadFile(config.PersistentFlagConfigurationFile)
+	if err != nil {
+		return err
+	}
+
+	var persisted persistedFlags
+	if err = json.Unmarshal(data, &persisted); err != nil {
+		return fmt.Errorf("invalid persistent flag configuration file %s: %v",
+			config.PersistentFlagConfigurationFile, err)
+	}
+
+	err = flagCache.UpdateCache([]byte(persisted.Content), persisted.Format)
+	if err != nil && !errors.Is(err, cache.ErrInvalidRules) {
+		return err
+	}
+	fflog.Printf(config.Logger, "info: flags loaded from the persistent file %s, retrieved at %s\n",
+		config.PersistentFlagConfigurationFile, persisted.Timestamp.Format(time.RFC3339))
+	return nil
+}
@@ -0,0 +1,89 @@
+package ffclient_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	ffclient "github.com/thomaspoignant/go-feature-flag"
+	"github.com/thomaspoignant/go-feature-flag/ffuser"
+)
+
+func TestPersistentFlagConfigurationFile(t *testing.T) {
+	persistentFile := filepath.Join(t.TempDir(), "flags.json")
+	user := ffuser.NewUser("random-key")
+
+	// a first start with a reachable retriever writes the persistent file.
+	gff, err := ffclient.New(ffclient.Config{
+		PollingInterval:                 5 * time.Second,
+		Retriever:                       &ffclient.FileRetriever{Path: "testdata/flag-config.yaml"},
+		PersistentFlagConfigurationFile: persistentFile,
+	})
+	assert.NoError(t, err)
+	gff.Close()
+	assert.FileExists(t, persistentFile)
+
+	// the retriever is unreachable, the flags are loaded from the persistent file.
+	gff, err = ffclient.New(ffclient.Config{
+		PollingInterval:                 5 * time.Second,
+		Retriever:                       &ffclient.FileRetriever{Path: "testdata/unknown-file.yaml"},
+		PersistentFlagConfigurationFile: persistentFile,
+	})
+	assert.NoError(t, err)
+	defer gff.Close()
+
+	got, err := gff.BoolVariation("test-flag", user, false)
+	assert.NoError(t, err)
+	assert.True(t, got)
+	assert.False(t, gff.GetCacheRefreshDate().IsZero())
+}
+
+func TestPersistentFlagConfigurationFile_unavailable(t *testing.T) {
+	tests := []struct {
+		name                    string
+		persistentFileContent   string
+		startWithRetrieverError bool
+		wantErr                 bool
+	}{
+		{
+			name:    "no persistent file",
+			wantErr: true,
+		},
+		{
+			name:                  "invalid persistent file",
+			persistentFileContent: `{"format": "yaml", "content": `,
+			wantErr:               true,
+		},
+		{
+			name:                    "invalid persistent file with StartWithRetrieverError",
+			persistentFileContent:   `{"format": "yaml", "content": `,
+			startWithRetrieverError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			persistentFile := filepath.Join(t.TempDir(), "flags.json")
+			if tt.persistentFileContent != "" {
+				assert.NoError(t, os.WriteFile(persistentFile, []byte(tt.persistentFileContent), 0o600))
+			}
+
+			gff, err := ffclient.New(ffclient.Config{
+				PollingInterval:                 5 * time.Second,
+				Retriever:                       &ffclient.FileRetriever{Path: "testdata/unknown-file.yaml"},
+				PersistentFlagConfigurationFile: persistentFile,
+				StartWithRetrieverError:         tt.startWithRetrieverError,
+			})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			defer gff.Close()
+
+			got, _ := gff.BoolVariation("test-flag", ffuser.NewUser("random-key"), false)
+			assert.False(t, got, "should use the SDK default value")
+		})
+	}
+}
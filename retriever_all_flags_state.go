@@ -0,0 +1,48 @@
+package ffclient
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/thomaspoignant/go-feature-flag/internal/flagstate"
+	"github.com/thomaspoignant/go-feature-flag/internal/flagv2"
+)
+
+// AllFlagsStateRetriever is a configuration struct to start go-feature-flag from the JSON of
+// an AllFlagsState (the output of flagstate.AllFlags.MarshalJSON).
+// The values have been computed for the user of the AllFlagsState, so every flag serves its precomputed
+// value whatever the user evaluated. The flags without value are not loaded, the default value is served.
+// The flags are generated in JSON and always decoded as JSON, the FileFormat must be empty or "json".
+type AllFlagsStateRetriever struct {
+	// Snapshot is the JSON of the AllFlagsState.
+	Snapshot []byte
+}
+
+// Retrieve is converting the AllFlagsState to a flag file with one variation per flag.
+func (r *AllFlagsStateRetriever) Retrieve(ctx context.Context) ([]byte, error) {
+	var allFlags flagstate.AllFlags
+	if err := json.Unmarshal(r.Snapshot, &allFlags); err != nil {
+		return nil, err
+	}
+
+	flags := make(map[string]flagv2.FlagData, len(allFlags.GetFlags()))
+	for key, state := range allFlags.GetFlags() {
+		if state.Value == nil {
+			continue
+		}
+		value := state.Value
+		variation := state.VariationType
+		trackEvents := state.TrackEvents
+		f := flagv2.FlagData{
+			Variations:  map[string]*interface{}{variation: &value},
+			DefaultRule: &flagv2.Rule{Variation: &variation},
+			TrackEvents: &trackEvents,
+		}
+		if state.Version != 0 {
+			version := state.Version
+			f.Version = &version
+		}
+		flags[key] = f
+	}
+	return json.Marshal(flags)
+}
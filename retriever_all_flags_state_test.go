@@ -0,0 +1,111 @@
+package ffclient_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	ffclient "github.com/thomaspoignant/go-feature-flag"
+	"github.com/thomaspoignant/go-feature-flag/ffuser"
+)
+
+func TestAllFlagsStateRetriever(t *testing.T) {
+	serverClient, err := ffclient.New(ffclient.Config{
+		PollingInterval: 5 * time.Second,
+		Retriever:       &ffclient.FileRetriever{Path: "testdata/ffclient/all_flags_options/flag-config.yaml"},
+	})
+	assert.NoError(t, err)
+	defer serverClient.Close()
+
+	betaUser := ffuser.NewUserBuilder("beta-user").AddCustom("beta", true).Build()
+	snapshot, err := serverClient.AllFlagsStateWithOptions(betaUser, ffclient.AllFlagsStateOptions{
+		WithReasons: true,
+	}).MarshalJSON()
+	assert.NoError(t, err)
+
+	client, err := ffclient.New(ffclient.Config{
+		PollingInterval: 5 * time.Second,
+		Retriever:       &ffclient.AllFlagsStateRetriever{Snapshot: snapshot},
+	})
+	assert.NoError(t, err)
+	defer client.Close()
+
+	// the values computed for the beta user are served to every user.
+	details, err := client.StringVariationDetails("checkout-color", ffuser.NewUser("random-key"), "black")
+	assert.NoError(t, err)
+	assert.Equal(t, "red", details.Value)
+	assert.Equal(t, "red", details.VariationName)
+	assert.Equal(t, float64(2), details.Version)
+
+	got, err := client.BoolVariation("new-checkout", ffuser.NewUser("random-key"), false)
+	assert.NoError(t, err)
+	assert.True(t, got)
+
+	_, err = client.BoolVariation("unknown-flag", ffuser.NewUser("random-key"), false)
+	assert.ErrorIs(t, err, ffclient.ErrFlagNotFound)
+}
+
+func TestAllFlagsStateRetriever_FileFormat(t *testing.T) {
+	snapshot := []byte(`{"flags":{"ratio":{"value":1,"timestamp":1622209328,"variationType":"one",
+"trackEvents":true}},"valid":true}`)
+
+	// the default FileFormat is yaml, the snapshot is still decoded as JSON and the float stays a float.
+	client, err := ffclient.New(ffclient.Config{
+		PollingInterval: 5 * time.Second,
+		Retriever:       &ffclient.AllFlagsStateRetriever{Snapshot: snapshot},
+	})
+	assert.NoError(t, err)
+	defer client.Close()
+
+	got, err := client.Float64Variation("ratio", ffuser.NewUser("random-key"), 0.5)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1), got)
+
+	_, err = ffclient.New(ffclient.Config{
+		PollingInterval: 5 * time.Second,
+		Retriever:       &ffclient.AllFlagsStateRetriever{Snapshot: snapshot},
+		FileFormat:      "toml",
+	})
+	assert.Error(t, err)
+}
+
+func TestAllFlagsStateRetriever_Retrieve(t *testing.T) {
+	tests := []struct {
+		name     string
+		snapshot string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name: "flags with values",
+			snapshot: `{"flags":{"max-workers":{"value":20,"timestamp":1622209328,"variationType":"large",
+"trackEvents":false,"version":1.1}},"valid":true}`,
+			want: `{"max-workers":{"variations":{"large":20},"defaultRule":{"variation":"large"},"trackEvents":false,
+"version":1.1}}`,
+		},
+		{
+			name: "flag without value is not loaded",
+			snapshot: `{"flags":{"checkout-color":{"timestamp":1622209328,"variationType":"blue",
+"trackEvents":true}},"valid":true}`,
+			want: `{}`,
+		},
+		{
+			name:     "invalid snapshot",
+			snapshot: `{"flags":`,
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := ffclient.AllFlagsStateRetriever{Snapshot: []byte(tt.snapshot)}
+			got, err := r.Retrieve(context.Background())
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(got))
+		})
+	}
+}
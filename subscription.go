@@ -0,0 +1,179 @@
+package ffclient
+
+import (
+	"reflect"
+
+	"github.com/thomaspoignant/go-feature-flag/ffuser"
+	"github.com/thomaspoignant/go-feature-flag/internal/cache"
+	"github.com/thomaspoignant/go-feature-flag/internal/flag"
+)
+
+// Flag is a flag as loaded from the flag file, it is the type received by the listeners of Subscribe.
+type Flag = flag.Flag
+
+// FlagChange describes a change of a flag.
+// Before is nil if the flag has been added and After is nil if the flag has been deleted.
+type FlagChange struct {
+	FlagKey string
+	Before  Flag
+	After   Flag
+}
+
+// Subscription is returned when you subscribe to the changes of a flag,
+// call Unsubscribe to stop receiving the changes.
+type Subscription struct {
+	subscription *cache.Subscription
+}
+
+// Unsubscribe stops the delivery of the changes, it can be called from the listener.
+func (s *Subscription) Unsubscribe() {
+	if s.subscription != nil {
+		s.subscription.Unsubscribe()
+	}
+}
+
+// Subscribe registers a listener called every time the flag changes in the flag file.
+// The listener is called in a dedicated goroutine with the changes in the order they happened,
+// a slow listener does not block the refresh of the flags.
+func Subscribe(flagKey string, listener func(before, after Flag)) *Subscription {
+	return ff.Subscribe(flagKey, listener)
+}
+
+// SubscribeChan returns a channel receiving the changes of the flag.
+// The channel is closed when you call Unsubscribe or when go-feature-flag is closed.
+func SubscribeChan(flagKey string) (<-chan FlagChange, *Subscription) {
+	return ff.SubscribeChan(flagKey)
+}
+
+// Subscribe registers a listener called every time the flag changes in the flag file.
+// The listener is called in a dedicated goroutine with the changes in the order they happened,
+// a slow listener does not block the refresh of the flags.
+// Note: Use this function only if you are using multiple go-feature-flag instances.
+func (g *GoFeatureFlag) Subscribe(flagKey string, listener func(before, after Flag)) *Subscription {
+	if g.notificationService == nil {
+		// in offline mode the flags never change.
+		return &Subscription{}
+	}
+	return &Subscription{subscription: g.notificationService.Subscribe(flagKey, listener)}
+}
+
+// SubscribeChan returns a channel receiving the changes of the flag.
+// The channel is closed when you call Unsubscribe or when go-feature-flag is closed.
+// Note: Use this function only if you are using multiple go-feature-flag instances.
+func (g *GoFeatureFlag) SubscribeChan(flagKey string) (<-chan FlagChange, *Subscription) {
+	changes := make(chan FlagChange)
+	if g.notificationService == nil {
+		// in offline mode the flags never change.
+		close(changes)
+		return changes, &Subscription{}
+	}
+
+	stop := make(chan struct{})
+	subscription := g.notificationService.Subscribe(flagKey, func(before, after Flag) {
+		select {
+		case changes <- FlagChange{FlagKey: flagKey, Before: before, After: after}:
+		case <-stop:
+		}
+	})
+	go func() {
+		// unblock the listener if nobody reads the channel anymore, and close the channel
+		// once the listener cannot send any change.
+		<-subscription.Stopped()
+		close(stop)
+		<-subscription.Done()
+		close(changes)
+	}()
+	return changes, &Subscription{subscription: subscription}
+}
+
+// valueListener is a flag evaluated for a user after every refresh of the flags,
+// the listener is called when the value changes.
+type valueListener struct {
+	flagKey   string
+	user      ffuser.User
+	listener  func(oldValue, newValue interface{})
+	lastValue interface{}
+}
+
+// SubscribeValue registers a listener called every time the value of the flag changes for this user.
+// The flag is evaluated again after every refresh of the flags, so a change of the flag file or a
+// progressive or scheduled rollout reaching a new step is detected within the PollingInterval.
+// The value is nil if the flag cannot be evaluated (ex: the flag has been deleted).
+func SubscribeValue(
+	flagKey string, user ffuser.User, listener func(oldValue, newValue interface{}),
+) *Subscription {
+	return ff.SubscribeValue(flagKey, user, listener)
+}
+
+// SubscribeValue registers a listener called every time the value of the flag changes for this user.
+// The flag is evaluated again after every refresh of the flags, so a change of the flag file or a
+// progressive or scheduled rollout reaching a new step is detected within the PollingInterval.
+// The value is nil if the flag cannot be evaluated (ex: the flag has been deleted).
+// Note: Use this function only if you are using multiple go-feature-flag instances.
+func (g *GoFeatureFlag) SubscribeValue(
+	flagKey string, user ffuser.User, listener func(oldValue, newValue interface{}),
+) *Subscription {
+	if g.config.Offline {
+		// in offline mode the flags are never evaluated.
+		return &Subscription{}
+	}
+
+	subscription := cache.NewSubscription(func(s *cache.Subscription) {
+		g.valueListenersMutex.Lock()
+		defer g.valueListenersMutex.Unlock()
+		delete(g.valueListeners, s)
+	})
+
+	g.valueListenersMutex.Lock()
+	defer g.valueListenersMutex.Unlock()
+	if g.valueListeners == nil {
+		g.valueListeners = map[*cache.Subscription]*valueListener{}
+	}
+	g.valueListeners[subscription] = &valueListener{
+		flagKey:   flagKey,
+		user:      user,
+		listener:  listener,
+		lastValue: g.currentValue(flagKey, user),
+	}
+	return &Subscription{subscription: subscription}
+}
+
+// notifyValueChanges evaluates the flags of the value listeners again,
+// a listener is called only if the value has changed for its user.
+func (g *GoFeatureFlag) notifyValueChanges() {
+	g.valueListenersMutex.Lock()
+	defer g.valueListenersMutex.Unlock()
+	for subscription, l := range g.valueListeners {
+		newValue := g.currentValue(l.flagKey, l.user)
+		if reflect.DeepEqual(l.lastValue, newValue) {
+			continue
+		}
+		oldValue, listener := l.lastValue, l.listener
+		l.lastValue = newValue
+		subscription.Push(func() { listener(oldValue, newValue) })
+	}
+}
+
+// closeValueListeners stops all the value listeners.
+func (g *GoFeatureFlag) closeValueListeners() {
+	g.valueListenersMutex.Lock()
+	subscriptions := make([]*cache.Subscription, 0, len(g.valueListeners))
+	for subscription := range g.valueListeners {
+		subscriptions = append(subscriptions, subscription)
+	}
+	g.valueListenersMutex.Unlock()
+
+	for _, subscription := range subscriptions {
+		subscription.Unsubscribe()
+	}
+}
+
+// currentValue evaluates the flag for the user without exporting the evaluation,
+// it returns nil if the flag cannot be evaluated.
+func (g *GoFeatureFlag) currentValue(flagKey string, user ffuser.User) interface{} {
+	value, _, err := g.rawVariation(flagKey, user, nil)
+	if err != nil {
+		return nil
+	}
+	return value
+}
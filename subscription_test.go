@@ -0,0 +1,180 @@
+package ffclient_test
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	ffclient "github.com/thomaspoignant/go-feature-flag"
+	"github.com/thomaspoignant/go-feature-flag/ffuser"
+)
+
+func TestSubscribe(t *testing.T) {
+	initialFileContent := `max-workers:
+  variations:
+    small: 5
+    large: 20
+  defaultRule:
+    variation: small`
+
+	flagFile, _ := ioutil.TempFile("", "")
+	_ = ioutil.WriteFile(flagFile.Name(), []byte(initialFileContent), 0o600)
+
+	gffClient, err := ffclient.New(ffclient.Config{
+		PollingInterval: 1 * time.Second,
+		Retriever:       &ffclient.FileRetriever{Path: flagFile.Name()},
+	})
+	assert.NoError(t, err)
+	defer gffClient.Close()
+
+	// the listener is declared with the exported types only, like in a module outside go-feature-flag.
+	listenerChanges := make(chan ffclient.FlagChange, 1)
+	listener := func(before, after ffclient.Flag) {
+		listenerChanges <- ffclient.FlagChange{FlagKey: "max-workers", Before: before, After: after}
+	}
+	subscription := gffClient.Subscribe("max-workers", listener)
+	defer subscription.Unsubscribe()
+	changes, chanSubscription := gffClient.SubscribeChan("max-workers")
+
+	updatedFileContent := `max-workers:
+  variations:
+    small: 5
+    large: 20
+  defaultRule:
+    variation: large`
+	_ = ioutil.WriteFile(flagFile.Name(), []byte(updatedFileContent), 0o600)
+
+	for _, received := range []<-chan ffclient.FlagChange{listenerChanges, changes} {
+		select {
+		case change := <-received:
+			assert.Equal(t, "max-workers", change.FlagKey)
+			assert.Equal(t, "small", change.Before.GetDefaultVariation())
+			assert.Equal(t, "large", change.After.GetDefaultVariation())
+		case <-time.After(5 * time.Second):
+			assert.Fail(t, "the change of the flag has not been received")
+		}
+	}
+
+	chanSubscription.Unsubscribe()
+	select {
+	case _, open := <-changes:
+		assert.False(t, open, "the channel should be closed after unsubscribe")
+	case <-time.After(time.Second):
+		assert.Fail(t, "the channel has not been closed")
+	}
+}
+
+func TestSubscribeChanOffline(t *testing.T) {
+	gffClient, err := ffclient.New(ffclient.Config{Offline: true})
+	assert.NoError(t, err)
+	defer gffClient.Close()
+
+	changes, subscription := gffClient.SubscribeChan("max-workers")
+	defer subscription.Unsubscribe()
+	_, open := <-changes
+	assert.False(t, open, "the flags never change in offline mode")
+}
+
+func TestSubscribeValue(t *testing.T) {
+	initialFileContent := `checkout-color:
+  variations:
+    blue: blue
+    red: red
+  targeting:
+    - query: beta eq true
+      variation: blue
+  defaultRule:
+    variation: blue`
+
+	flagFile, _ := ioutil.TempFile("", "")
+	_ = ioutil.WriteFile(flagFile.Name(), []byte(initialFileContent), 0o600)
+
+	gffClient, err := ffclient.New(ffclient.Config{
+		PollingInterval: 1 * time.Second,
+		Retriever:       &ffclient.FileRetriever{Path: flagFile.Name()},
+	})
+	assert.NoError(t, err)
+	defer gffClient.Close()
+
+	type valueChange struct{ oldValue, newValue interface{} }
+	betaChanges := make(chan valueChange, 10)
+	betaSubscription := gffClient.SubscribeValue("checkout-color",
+		ffuser.NewUserBuilder("beta-user").AddCustom("beta", true).Build(),
+		func(oldValue, newValue interface{}) { betaChanges <- valueChange{oldValue, newValue} })
+	defer betaSubscription.Unsubscribe()
+	otherChanges := make(chan valueChange, 10)
+	otherSubscription := gffClient.SubscribeValue("checkout-color", ffuser.NewUser("other-user"),
+		func(oldValue, newValue interface{}) { otherChanges <- valueChange{oldValue, newValue} })
+	defer otherSubscription.Unsubscribe()
+
+	// only the users outside the beta see a new value.
+	updatedFileContent := `checkout-color:
+  variations:
+    blue: blue
+    red: red
+  targeting:
+    - query: beta eq true
+      variation: blue
+  defaultRule:
+    variation: red`
+	_ = ioutil.WriteFile(flagFile.Name(), []byte(updatedFileContent), 0o600)
+
+	select {
+	case change := <-otherChanges:
+		assert.Equal(t, valueChange{"blue", "red"}, change)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "the change of the value has not been received")
+	}
+
+	// the flag is evaluated again at every refresh, the listener is called only if the value has changed.
+	time.Sleep(2 * time.Second)
+	assert.Empty(t, betaChanges)
+	assert.Empty(t, otherChanges)
+
+	_ = ioutil.WriteFile(flagFile.Name(), []byte(`another-flag:
+  variations:
+    A: A
+  defaultRule:
+    variation: A`), 0o600)
+	select {
+	case change := <-betaChanges:
+		assert.Equal(t, valueChange{"blue", nil}, change, "the value is nil once the flag is deleted")
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "the deletion of the flag has not been received")
+	}
+}
+
+func TestSubscribeValueScheduledRollout(t *testing.T) {
+	flagFile, _ := ioutil.TempFile("", "")
+	_ = ioutil.WriteFile(flagFile.Name(), []byte(`scheduled-flag:
+  true: "new"
+  false: "old"
+  default: "old"
+  percentage: 0
+  rollout:
+    scheduled:
+      steps:
+        - date: `+time.Now().Add(2*time.Second).Format(time.RFC3339Nano)+`
+          percentage: 100`), 0o600)
+
+	gffClient, err := ffclient.New(ffclient.Config{
+		PollingInterval: 1 * time.Second,
+		Retriever:       &ffclient.FileRetriever{Path: flagFile.Name()},
+	})
+	assert.NoError(t, err)
+	defer gffClient.Close()
+
+	changes := make(chan []interface{}, 1)
+	subscription := gffClient.SubscribeValue("scheduled-flag", ffuser.NewUser("random-key"),
+		func(oldValue, newValue interface{}) { changes <- []interface{}{oldValue, newValue} })
+	defer subscription.Unsubscribe()
+
+	// the flag file never changes, the new value comes from the scheduled step.
+	select {
+	case change := <-changes:
+		assert.Equal(t, []interface{}{"old", "new"}, change)
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "the scheduled step has not been applied")
+	}
+}
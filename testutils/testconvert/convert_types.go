@@ -12,6 +12,11 @@ func Time(t time.Time) *time.Time {
 	return &t
 }
 
+// Int returns a pointer to the int value passed in.
+func Int(v int) *int {
+	return &v
+}
+
 // Float64 returns a pointer to the float64 value passed in.
 func Float64(t float64) *float64 {
 	return &t
@@ -1,7 +1,8 @@
 package ffclient
 
 import (
-	"fmt"
+	"context"
+	"errors"
 
 	"github.com/thomaspoignant/go-feature-flag/ffexporter"
 	"github.com/thomaspoignant/go-feature-flag/ffuser"
@@ -10,12 +11,11 @@ import (
 	"github.com/thomaspoignant/go-feature-flag/internal/model"
 )
 
-const (
-	errorFlagNotAvailable = "flag %v is not present or disabled"
-	errorWrongVariation   = "wrong variation used for flag %v"
-)
-
-var offlineVariationResult = model.VariationResult{VariationType: flag.VariationSDKDefault, Failed: true}
+var offlineVariationResult = model.VariationResult{
+	VariationType: flag.VariationSDKDefault,
+	Failed:        true,
+	Reason:        flag.ReasonOffline,
+}
 
 // BoolVariation return the value of the flag in boolean.
 // An error is return if you don't have init the library before calling the function.
@@ -24,6 +24,11 @@ func BoolVariation(flagKey string, user ffuser.User, defaultValue bool) (bool, e
 	return ff.BoolVariation(flagKey, user, defaultValue)
 }
 
+// BoolVariationWithContext is the same as BoolVariation, the context is passed to the hooks.
+func BoolVariationWithContext(ctx context.Context, flagKey string, user ffuser.User, defaultValue bool) (bool, error) {
+	return ff.BoolVariationWithContext(ctx, flagKey, user, defaultValue)
+}
+
 // IntVariation return the value of the flag in int.
 // An error is return if you don't have init the library before calling the function.
 // If the key does not exist we return the default value.
@@ -31,6 +36,11 @@ func IntVariation(flagKey string, user ffuser.User, defaultValue int) (int, erro
 	return ff.IntVariation(flagKey, user, defaultValue)
 }
 
+// IntVariationWithContext is the same as IntVariation, the context is passed to the hooks.
+func IntVariationWithContext(ctx context.Context, flagKey string, user ffuser.User, defaultValue int) (int, error) {
+	return ff.IntVariationWithContext(ctx, flagKey, user, defaultValue)
+}
+
 // Float64Variation return the value of the flag in float64.
 // An error is return if you don't have init the library before calling the function.
 // If the key does not exist we return the default value.
@@ -38,6 +48,13 @@ func Float64Variation(flagKey string, user ffuser.User, defaultValue float64) (f
 	return ff.Float64Variation(flagKey, user, defaultValue)
 }
 
+// Float64VariationWithContext is the same as Float64Variation, the context is passed to the hooks.
+func Float64VariationWithContext(
+	ctx context.Context, flagKey string, user ffuser.User, defaultValue float64,
+) (float64, error) {
+	return ff.Float64VariationWithContext(ctx, flagKey, user, defaultValue)
+}
+
 // StringVariation return the value of the flag in string.
 // An error is return if you don't have init the library before calling the function.
 // If the key does not exist we return the default value.
@@ -45,6 +62,13 @@ func StringVariation(flagKey string, user ffuser.User, defaultValue string) (str
 	return ff.StringVariation(flagKey, user, defaultValue)
 }
 
+// StringVariationWithContext is the same as StringVariation, the context is passed to the hooks.
+func StringVariationWithContext(
+	ctx context.Context, flagKey string, user ffuser.User, defaultValue string,
+) (string, error) {
+	return ff.StringVariationWithContext(ctx, flagKey, user, defaultValue)
+}
+
 // JSONArrayVariation return the value of the flag in []interface{}.
 // An error is return if you don't have init the library before calling the function.
 // If the key does not exist we return the default value.
@@ -52,6 +76,13 @@ func JSONArrayVariation(flagKey string, user ffuser.User, defaultValue []interfa
 	return ff.JSONArrayVariation(flagKey, user, defaultValue)
 }
 
+// JSONArrayVariationWithContext is the same as JSONArrayVariation, the context is passed to the hooks.
+func JSONArrayVariationWithContext(
+	ctx context.Context, flagKey string, user ffuser.User, defaultValue []interface{},
+) ([]interface{}, error) {
+	return ff.JSONArrayVariationWithContext(ctx, flagKey, user, defaultValue)
+}
+
 // JSONVariation return the value of the flag in map[string]interface{}.
 // An error is return if you don't have init the library before calling the function.
 // If the key does not exist we return the default value.
@@ -61,6 +92,13 @@ func JSONVariation(
 	return ff.JSONVariation(flagKey, user, defaultValue)
 }
 
+// JSONVariationWithContext is the same as JSONVariation, the context is passed to the hooks.
+func JSONVariationWithContext(
+	ctx context.Context, flagKey string, user ffuser.User, defaultValue map[string]interface{},
+) (map[string]interface{}, error) {
+	return ff.JSONVariationWithContext(ctx, flagKey, user, defaultValue)
+}
+
 // AllFlagsState return the values of all the flags for a specific user.
 // If valid field is false it means that we had an error when checking the flags.
 func AllFlagsState(user ffuser.User) flagstate.AllFlags {
@@ -79,7 +117,15 @@ func GetFlagsFromCache() (map[string]flag.Flag, error) {
 // If the key does not exist we return the default value.
 // Note: Use this function only if you are using multiple go-feature-flag instances.
 func (g *GoFeatureFlag) BoolVariation(flagKey string, user ffuser.User, defaultValue bool) (bool, error) {
-	res, err := g.boolVariation(flagKey, user, defaultValue)
+	return g.BoolVariationWithContext(context.Background(), flagKey, user, defaultValue)
+}
+
+// BoolVariationWithContext is the same as BoolVariation, the context is passed to the hooks.
+// Note: Use this function only if you are using multiple go-feature-flag instances.
+func (g *GoFeatureFlag) BoolVariationWithContext(
+	ctx context.Context, flagKey string, user ffuser.User, defaultValue bool,
+) (bool, error) {
+	res, err := g.boolVariation(ctx, flagKey, user, defaultValue)
 	g.notifyVariation(flagKey, user, res.VariationResult, res.Value)
 	return res.Value, err
 }
@@ -89,7 +135,15 @@ func (g *GoFeatureFlag) BoolVariation(flagKey string, user ffuser.User, defaultV
 // If the key does not exist we return the default value.
 // Note: Use this function only if you are using multiple go-feature-flag instances.
 func (g *GoFeatureFlag) IntVariation(flagKey string, user ffuser.User, defaultValue int) (int, error) {
-	res, err := g.intVariation(flagKey, user, defaultValue)
+	return g.IntVariationWithContext(context.Background(), flagKey, user, defaultValue)
+}
+
+// IntVariationWithContext is the same as IntVariation, the context is passed to the hooks.
+// Note: Use this function only if you are using multiple go-feature-flag instances.
+func (g *GoFeatureFlag) IntVariationWithContext(
+	ctx context.Context, flagKey string, user ffuser.User, defaultValue int,
+) (int, error) {
+	res, err := g.intVariation(ctx, flagKey, user, defaultValue)
 	g.notifyVariation(flagKey, user, res.VariationResult, res.Value)
 	return res.Value, err
 }
@@ -99,7 +153,15 @@ func (g *GoFeatureFlag) IntVariation(flagKey string, user ffuser.User, defaultVa
 // If the key does not exist we return the default value.
 // Note: Use this function only if you are using multiple go-feature-flag instances.
 func (g *GoFeatureFlag) Float64Variation(flagKey string, user ffuser.User, defaultValue float64) (float64, error) {
-	res, err := g.float64Variation(flagKey, user, defaultValue)
+	return g.Float64VariationWithContext(context.Background(), flagKey, user, defaultValue)
+}
+
+// Float64VariationWithContext is the same as Float64Variation, the context is passed to the hooks.
+// Note: Use this function only if you are using multiple go-feature-flag instances.
+func (g *GoFeatureFlag) Float64VariationWithContext(
+	ctx context.Context, flagKey string, user ffuser.User, defaultValue float64,
+) (float64, error) {
+	res, err := g.float64Variation(ctx, flagKey, user, defaultValue)
 	g.notifyVariation(flagKey, user, res.VariationResult, res.Value)
 	return res.Value, err
 }
@@ -109,7 +171,15 @@ func (g *GoFeatureFlag) Float64Variation(flagKey string, user ffuser.User, defau
 // If the key does not exist we return the default value.
 // Note: Use this function only if you are using multiple go-feature-flag instances.
 func (g *GoFeatureFlag) StringVariation(flagKey string, user ffuser.User, defaultValue string) (string, error) {
-	res, err := g.stringVariation(flagKey, user, defaultValue)
+	return g.StringVariationWithContext(context.Background(), flagKey, user, defaultValue)
+}
+
+// StringVariationWithContext is the same as StringVariation, the context is passed to the hooks.
+// Note: Use this function only if you are using multiple go-feature-flag instances.
+func (g *GoFeatureFlag) StringVariationWithContext(
+	ctx context.Context, flagKey string, user ffuser.User, defaultValue string,
+) (string, error) {
+	res, err := g.stringVariation(ctx, flagKey, user, defaultValue)
 	g.notifyVariation(flagKey, user, res.VariationResult, res.Value)
 	return res.Value, err
 }
@@ -121,7 +191,15 @@ func (g *GoFeatureFlag) StringVariation(flagKey string, user ffuser.User, defaul
 func (g *GoFeatureFlag) JSONArrayVariation(
 	flagKey string, user ffuser.User, defaultValue []interface{},
 ) ([]interface{}, error) {
-	res, err := g.jsonArrayVariation(flagKey, user, defaultValue)
+	return g.JSONArrayVariationWithContext(context.Background(), flagKey, user, defaultValue)
+}
+
+// JSONArrayVariationWithContext is the same as JSONArrayVariation, the context is passed to the hooks.
+// Note: Use this function only if you are using multiple go-feature-flag instances.
+func (g *GoFeatureFlag) JSONArrayVariationWithContext(
+	ctx context.Context, flagKey string, user ffuser.User, defaultValue []interface{},
+) ([]interface{}, error) {
+	res, err := g.jsonArrayVariation(ctx, flagKey, user, defaultValue)
 	g.notifyVariation(flagKey, user, res.VariationResult, res.Value)
 	return res.Value, err
 }
@@ -133,248 +211,125 @@ func (g *GoFeatureFlag) JSONArrayVariation(
 func (g *GoFeatureFlag) JSONVariation(
 	flagKey string, user ffuser.User, defaultValue map[string]interface{},
 ) (map[string]interface{}, error) {
-	res, err := g.jsonVariation(flagKey, user, defaultValue)
+	return g.JSONVariationWithContext(context.Background(), flagKey, user, defaultValue)
+}
+
+// JSONVariationWithContext is the same as JSONVariation, the context is passed to the hooks.
+// Note: Use this function only if you are using multiple go-feature-flag instances.
+func (g *GoFeatureFlag) JSONVariationWithContext(
+	ctx context.Context, flagKey string, user ffuser.User, defaultValue map[string]interface{},
+) (map[string]interface{}, error) {
+	res, err := g.jsonVariation(ctx, flagKey, user, defaultValue)
 	g.notifyVariation(flagKey, user, res.VariationResult, res.Value)
 	return res.Value, err
 }
 
 // AllFlagsState return a flagstate.AllFlags that contains all the flags for a specific user.
 func (g *GoFeatureFlag) AllFlagsState(user ffuser.User) flagstate.AllFlags {
-	flags := map[string]flag.Flag{}
-
-	if !g.config.Offline {
-		var err error
-		flags, err = g.cache.AllFlags()
-		if err != nil {
-			// empty AllFlags will set valid to false
-			return flagstate.AllFlags{}
-		}
-	}
-
-	allFlags := flagstate.NewAllFlags()
-	for key, currentFlag := range flags {
-		flagValue, varType := currentFlag.Value(key, user, g.config.Environment)
-		switch v := flagValue; v.(type) {
-		case int, float64, bool, string, []interface{}, map[string]interface{}:
-			allFlags.AddFlag(key, flagstate.NewFlagState(currentFlag.GetTrackEvents(), v, varType, false))
-
-		default:
-			defaultVariationName := currentFlag.GetDefaultVariation()
-			defaultVariationValue := currentFlag.GetVariationValue(defaultVariationName)
-			allFlags.AddFlag(
-				key, flagstate.NewFlagState(currentFlag.GetTrackEvents(), defaultVariationValue, defaultVariationName, true))
-		}
-	}
-	return allFlags
+	return g.AllFlagsStateWithOptions(user, AllFlagsStateOptions{})
 }
 
 // GetFlagsFromCache returns all the flags present in the cache with their
 // current state when calling this method. If cache hasn't been initialized, an
 // error reporting this is returned.
 func (g *GoFeatureFlag) GetFlagsFromCache() (map[string]flag.Flag, error) {
-	return g.cache.AllFlags()
+	flags, err := g.cache.AllFlags()
+	if err != nil {
+		return nil, err
+	}
+	// the map of the cache is shared, we return a copy to keep the cache untouched.
+	flagsCopy := make(map[string]flag.Flag, len(flags))
+	for key, f := range flags {
+		flagsCopy[key] = f
+	}
+	return flagsCopy, nil
 }
 
 // boolVariation is the internal func that handle the logic of a variation with a bool value
 // the result will always contains a valid model.BoolVarResult
-func (g *GoFeatureFlag) boolVariation(flagKey string, user ffuser.User, sdkDefaultValue bool,
+func (g *GoFeatureFlag) boolVariation(ctx context.Context, flagKey string, user ffuser.User, sdkDefaultValue bool,
 ) (model.BoolVarResult, error) {
-	if g.config.Offline {
-		return model.BoolVarResult{Value: sdkDefaultValue, VariationResult: offlineVariationResult}, nil
-	}
-
-	f, err := g.getFlagFromCache(flagKey)
-	if err != nil {
-		return model.BoolVarResult{
-			Value:           sdkDefaultValue,
-			VariationResult: computeVariationResult(f, flag.VariationSDKDefault, true),
-		}, err
-	}
-
-	flagValue, variationType := f.Value(flagKey, user, g.config.Environment)
-	res, ok := flagValue.(bool)
-	if !ok {
-		return model.BoolVarResult{
-			Value:           sdkDefaultValue,
-			VariationResult: computeVariationResult(f, flag.VariationSDKDefault, true),
-		}, fmt.Errorf(errorWrongVariation, flagKey)
-	}
-	return model.BoolVarResult{
-		Value:           res,
-		VariationResult: computeVariationResult(f, variationType, false),
-	}, nil
+	value, varResult, err := variation(ctx, g, flagKey, user, sdkDefaultValue)
+	return model.BoolVarResult{Value: value, VariationResult: varResult}, err
 }
 
 // intVariation is the internal func that handle the logic of a variation with an int value
 // the result will always contains a valid model.IntVarResult
-func (g *GoFeatureFlag) intVariation(flagKey string, user ffuser.User, sdkDefaultValue int,
+func (g *GoFeatureFlag) intVariation(ctx context.Context, flagKey string, user ffuser.User, sdkDefaultValue int,
 ) (model.IntVarResult, error) {
-	if g.config.Offline {
-		return model.IntVarResult{Value: sdkDefaultValue, VariationResult: offlineVariationResult}, nil
-	}
-
-	f, err := g.getFlagFromCache(flagKey)
-	if err != nil {
-		return model.IntVarResult{
-			Value:           sdkDefaultValue,
-			VariationResult: computeVariationResult(f, flag.VariationSDKDefault, true),
-		}, err
-	}
-
-	flagValue, variationType := f.Value(flagKey, user, g.config.Environment)
-	res, ok := flagValue.(int)
-	if !ok {
-		// if this is a float64 we convert it to int
-		if resFloat, okFloat := flagValue.(float64); okFloat {
-			return model.IntVarResult{
-				Value:           int(resFloat),
-				VariationResult: computeVariationResult(f, variationType, false),
-			}, nil
-		}
-
-		return model.IntVarResult{
-			Value:           sdkDefaultValue,
-			VariationResult: computeVariationResult(f, flag.VariationSDKDefault, true),
-		}, fmt.Errorf(errorWrongVariation, flagKey)
-	}
-	return model.IntVarResult{
-		Value:           res,
-		VariationResult: computeVariationResult(f, variationType, false),
-	}, nil
+	value, varResult, err := variation(ctx, g, flagKey, user, sdkDefaultValue)
+	return model.IntVarResult{Value: value, VariationResult: varResult}, err
 }
 
 // float64Variation is the internal func that handle the logic of a variation with a float64 value
 // the result will always contains a valid model.Float64VarResult
-func (g *GoFeatureFlag) float64Variation(flagKey string, user ffuser.User, sdkDefaultValue float64,
+func (g *GoFeatureFlag) float64Variation(ctx context.Context, flagKey string, user ffuser.User, sdkDefaultValue float64,
 ) (model.Float64VarResult, error) {
-	if g.config.Offline {
-		return model.Float64VarResult{Value: sdkDefaultValue, VariationResult: offlineVariationResult}, nil
-	}
-
-	f, err := g.getFlagFromCache(flagKey)
-	if err != nil {
-		return model.Float64VarResult{
-			Value:           sdkDefaultValue,
-			VariationResult: computeVariationResult(f, flag.VariationSDKDefault, true),
-		}, err
-	}
-
-	flagValue, variationType := f.Value(flagKey, user, g.config.Environment)
-	res, ok := flagValue.(float64)
-	if !ok {
-		return model.Float64VarResult{
-			Value:           sdkDefaultValue,
-			VariationResult: computeVariationResult(f, flag.VariationSDKDefault, true),
-		}, fmt.Errorf(errorWrongVariation, flagKey)
-	}
-	return model.Float64VarResult{
-		Value:           res,
-		VariationResult: computeVariationResult(f, variationType, false),
-	}, nil
+	value, varResult, err := variation(ctx, g, flagKey, user, sdkDefaultValue)
+	return model.Float64VarResult{Value: value, VariationResult: varResult}, err
 }
 
 // stringVariation is the internal func that handle the logic of a variation with a string value
 // the result will always contains a valid model.StringVarResult
-func (g *GoFeatureFlag) stringVariation(flagKey string, user ffuser.User, sdkDefaultValue string,
+func (g *GoFeatureFlag) stringVariation(ctx context.Context, flagKey string, user ffuser.User, sdkDefaultValue string,
 ) (model.StringVarResult, error) {
-	if g.config.Offline {
-		return model.StringVarResult{Value: sdkDefaultValue, VariationResult: offlineVariationResult}, nil
-	}
-
-	f, err := g.getFlagFromCache(flagKey)
-	if err != nil {
-		return model.StringVarResult{
-			Value:           sdkDefaultValue,
-			VariationResult: computeVariationResult(f, flag.VariationSDKDefault, true),
-		}, err
-	}
-
-	flagValue, variationType := f.Value(flagKey, user, g.config.Environment)
-	res, ok := flagValue.(string)
-	if !ok {
-		return model.StringVarResult{
-			Value:           sdkDefaultValue,
-			VariationResult: computeVariationResult(f, flag.VariationSDKDefault, true),
-		}, fmt.Errorf(errorWrongVariation, flagKey)
-	}
-	return model.StringVarResult{
-		Value:           res,
-		VariationResult: computeVariationResult(f, variationType, false),
-	}, nil
+	value, varResult, err := variation(ctx, g, flagKey, user, sdkDefaultValue)
+	return model.StringVarResult{Value: value, VariationResult: varResult}, err
 }
 
 // jsonArrayVariation is the internal func that handle the logic of a variation with a json value
 // the result will always contains a valid model.JSONArrayVarResult
-func (g *GoFeatureFlag) jsonArrayVariation(flagKey string, user ffuser.User, sdkDefaultValue []interface{},
+func (g *GoFeatureFlag) jsonArrayVariation(
+	ctx context.Context, flagKey string, user ffuser.User, sdkDefaultValue []interface{},
 ) (model.JSONArrayVarResult, error) {
-	if g.config.Offline {
-		return model.JSONArrayVarResult{Value: sdkDefaultValue, VariationResult: offlineVariationResult}, nil
-	}
-
-	f, err := g.getFlagFromCache(flagKey)
-	if err != nil {
-		return model.JSONArrayVarResult{
-			Value:           sdkDefaultValue,
-			VariationResult: computeVariationResult(f, flag.VariationSDKDefault, true),
-		}, err
-	}
-
-	flagValue, variationType := f.Value(flagKey, user, g.config.Environment)
-	res, ok := flagValue.([]interface{})
-	if !ok {
-		return model.JSONArrayVarResult{
-			Value:           sdkDefaultValue,
-			VariationResult: computeVariationResult(f, flag.VariationSDKDefault, true),
-		}, fmt.Errorf(errorWrongVariation, flagKey)
-	}
-	return model.JSONArrayVarResult{
-		Value:           res,
-		VariationResult: computeVariationResult(f, variationType, false),
-	}, nil
+	value, varResult, err := variation(ctx, g, flagKey, user, sdkDefaultValue)
+	return model.JSONArrayVarResult{Value: value, VariationResult: varResult}, err
 }
 
 // jsonVariation is the internal func that handle the logic of a variation with a json value
 // the result will always contains a valid model.JSONVarResult
-func (g *GoFeatureFlag) jsonVariation(flagKey string, user ffuser.User, sdkDefaultValue map[string]interface{},
+func (g *GoFeatureFlag) jsonVariation(
+	ctx context.Context, flagKey string, user ffuser.User, sdkDefaultValue map[string]interface{},
 ) (model.JSONVarResult, error) {
-	if g.config.Offline {
-		return model.JSONVarResult{Value: sdkDefaultValue, VariationResult: offlineVariationResult}, nil
-	}
+	value, varResult, err := variation(ctx, g, flagKey, user, sdkDefaultValue)
+	return model.JSONVarResult{Value: value, VariationResult: varResult}, err
+}
 
-	f, err := g.getFlagFromCache(flagKey)
-	if err != nil {
-		return model.JSONVarResult{
-			Value:           sdkDefaultValue,
-			VariationResult: computeVariationResult(f, flag.VariationSDKDefault, true),
-		}, err
+// computeVariationResult is creating a model.VariationResult for a successful evaluation.
+func computeVariationResult(f flag.Flag, resolutionDetails flag.ResolutionDetails) model.VariationResult {
+	varResult := model.VariationResult{
+		VariationType:        resolutionDetails.Variant,
+		Reason:               resolutionDetails.Reason,
+		RuleIndex:            resolutionDetails.RuleIndex,
+		RuleName:             resolutionDetails.RuleName,
+		PrerequisiteKey:      resolutionDetails.PrerequisiteKey,
+		BucketingKeyFallback: resolutionDetails.BucketingKeyFallback,
 	}
-
-	flagValue, variationType := f.Value(flagKey, user, g.config.Environment)
-	res, ok := flagValue.(map[string]interface{})
-	if !ok {
-		return model.JSONVarResult{
-			Value:           sdkDefaultValue,
-			VariationResult: computeVariationResult(f, flag.VariationSDKDefault, true),
-		}, fmt.Errorf(errorWrongVariation, flagKey)
+	if f != nil {
+		varResult.TrackEvents = f.GetTrackEvents()
+		varResult.Version = f.GetVersion()
 	}
-	return model.JSONVarResult{
-		Value:           res,
-		VariationResult: computeVariationResult(f, variationType, false),
-	}, nil
+	return varResult
 }
 
-// computeVariationResult is creating a model.VariationResult
-func computeVariationResult(flag flag.Flag, variationType string, failed bool) model.VariationResult {
+// computeErrorVariationResult is creating the model.VariationResult used when the SDK default
+// value is served, the error explains why the flag has not been evaluated.
+func computeErrorVariationResult(f flag.Flag, err error) model.VariationResult {
 	varResult := model.VariationResult{
-		VariationType: variationType,
-		Failed:        failed,
+		VariationType: flag.VariationSDKDefault,
+		Failed:        true,
+		Reason:        flag.ReasonError,
+		ErrorCode:     flag.ErrorCodeGeneral,
 	}
-
-	if flag != nil {
-		varResult.TrackEvents = flag.GetTrackEvents()
-		varResult.Version = flag.GetVersion()
+	var evalErr *EvaluationError
+	if errors.As(err, &evalErr) {
+		varResult.Reason = evalErr.Reason
+		varResult.ErrorCode = evalErr.ErrorCode
+	}
+	if f != nil {
+		varResult.TrackEvents = f.GetTrackEvents()
+		varResult.Version = f.GetVersion()
 	}
-
 	return varResult
 }
 
@@ -385,27 +340,42 @@ func computeVariationResult(flag flag.Flag, variationType string, failed bool) m
 // Note: Use this function only if you are using multiple go-feature-flag instances.
 func (g *GoFeatureFlag) RawVariation(flagKey string, user ffuser.User, sdkDefaultValue interface{},
 ) (model.RawVarResult, error) {
+	value, varResult, err := evaluateWithHooks(context.Background(), g, flagKey, user, sdkDefaultValue,
+		func() (interface{}, model.VariationResult, error) {
+			return g.rawVariation(flagKey, user, sdkDefaultValue)
+		})
+	res := model.RawVarResult{Value: value, VariationResult: varResult}
+	g.notifyVariation(flagKey, user, res.VariationResult, res.Value)
+	return res, err
+}
+
+// rawVariation evaluates the flag without checking the type of the value.
+func (g *GoFeatureFlag) rawVariation(flagKey string, user ffuser.User, sdkDefaultValue interface{},
+) (interface{}, model.VariationResult, error) {
 	if g.config.Offline {
-		return model.RawVarResult{Value: sdkDefaultValue, VariationResult: offlineVariationResult}, nil
+		return sdkDefaultValue, offlineVariationResult, newOfflineError(flagKey)
 	}
+	return g.rawVariationFrom(g.cache, flagKey, user, sdkDefaultValue)
+}
 
-	f, err := g.getFlagFromCache(flagKey)
+// rawVariationFrom evaluates the flag read from the reader without checking the type of the value.
+func (g *GoFeatureFlag) rawVariationFrom(reader flagReader, flagKey string, user ffuser.User,
+	sdkDefaultValue interface{},
+) (interface{}, model.VariationResult, error) {
+	f, err := getFlagFrom(reader, flagKey)
 	if err != nil {
-		res := model.RawVarResult{
-			Value:           sdkDefaultValue,
-			VariationResult: computeVariationResult(f, flag.VariationSDKDefault, true),
-		}
-		g.notifyVariation(flagKey, user, res.VariationResult, res.Value)
-		return res, err
+		return sdkDefaultValue, computeErrorVariationResult(f, err), err
 	}
 
-	flagValue, variationType := f.Value(flagKey, user, g.config.Environment)
-	res := model.RawVarResult{
-		Value:           flagValue,
-		VariationResult: computeVariationResult(f, variationType, false),
+	flagValue, resolutionDetails, err := g.evaluateFrom(reader, flagKey, f, user)
+	if err != nil {
+		return sdkDefaultValue, computeErrorVariationResult(f, err), err
 	}
-	g.notifyVariation(flagKey, user, res.VariationResult, res.Value)
-	return res, nil
+	if resolutionDetails.Variant == "" {
+		err := newNoVariationError(flagKey, resolutionDetails.Reason)
+		return sdkDefaultValue, computeErrorVariationResult(f, err), err
+	}
+	return flagValue, computeVariationResult(f, resolutionDetails), nil
 }
 
 // notifyVariation is logging the evaluation result for a flag
@@ -427,11 +397,20 @@ func (g *GoFeatureFlag) notifyVariation(
 }
 
 // getFlagFromCache try to get the flag from the cache.
-// It returns an error if the cache is not init or if the flag is not present or disabled.
+// It returns an EvaluationError if the cache is not init or if the flag is not present or disabled.
 func (g *GoFeatureFlag) getFlagFromCache(flagKey string) (flag.Flag, error) {
-	flag, err := g.cache.GetFlag(flagKey)
-	if err != nil || flag.GetDisable() {
-		return flag, fmt.Errorf(errorFlagNotAvailable, flagKey)
+	return getFlagFrom(g.cache, flagKey)
+}
+
+// getFlagFrom try to get the flag from the reader.
+// It returns an EvaluationError if the cache is not init or if the flag is not present or disabled.
+func getFlagFrom(reader flagReader, flagKey string) (flag.Flag, error) {
+	f, err := reader.GetFlag(flagKey)
+	if err != nil {
+		return f, newFlagNotAvailableError(flagKey, err)
+	}
+	if f.GetDisable() {
+		return f, newFlagDisabledError(flagKey)
 	}
-	return flag, nil
+	return f, nil
 }
@@ -0,0 +1,321 @@
+package ffclient
+
+import (
+	"context"
+
+	"github.com/thomaspoignant/go-feature-flag/ffuser"
+	"github.com/thomaspoignant/go-feature-flag/internal/flag"
+	"github.com/thomaspoignant/go-feature-flag/internal/model"
+)
+
+// EvaluationReason is explaining why a variation has been served to the user.
+type EvaluationReason = flag.ResolutionReason
+
+const (
+	ReasonDefault              = flag.ReasonDefault
+	ReasonTargetMatch          = flag.ReasonTargetMatch
+	ReasonRuleMatch            = flag.ReasonRuleMatch
+	ReasonPercentage           = flag.ReasonPercentage
+	ReasonDisabled             = flag.ReasonDisabled
+	ReasonExperimentNotRunning = flag.ReasonExperimentNotRunning
+	ReasonPrerequisiteFailed   = flag.ReasonPrerequisiteFailed
+	ReasonFlagNotFound         = flag.ReasonFlagNotFound
+	ReasonTypeMismatch         = flag.ReasonTypeMismatch
+	ReasonOffline              = flag.ReasonOffline
+	ReasonError                = flag.ReasonError
+)
+
+// ErrorCode identifies the error that prevented the evaluation of the flag.
+type ErrorCode = flag.ErrorCode
+
+const (
+	ErrorCodeFlagNotFound = flag.ErrorCodeFlagNotFound
+	ErrorCodeTypeMismatch = flag.ErrorCodeTypeMismatch
+	ErrorCodeGeneral      = flag.ErrorCodeGeneral
+)
+
+// EvaluationDetails contains the information explaining how the value of a flag has been computed.
+type EvaluationDetails struct {
+	// VariationName is the name of the variation served, it is SdkDefault if the default value
+	// passed to the variation function is used.
+	VariationName string `json:"variationName"`
+
+	// Reason is explaining why this variation has been served.
+	Reason EvaluationReason `json:"reason"`
+
+	// RuleIndex is the index of the targeting rule that applied to the user, it is set only
+	// if the reason is RULE_MATCH.
+	RuleIndex *int `json:"ruleIndex,omitempty"`
+
+	// RuleName is the name of the targeting rule that applied to the user.
+	RuleName *string `json:"ruleName,omitempty"`
+
+	// PrerequisiteKey is the key of the prerequisite flag that failed.
+	PrerequisiteKey *string `json:"prerequisiteKey,omitempty"`
+
+	// BucketingKeyFallback is true if the user key has been used because the bucketing key is missing.
+	BucketingKeyFallback bool `json:"bucketingKeyFallback,omitempty"`
+
+	// ErrorCode is set if the flag has not been evaluated and the default value is served.
+	ErrorCode ErrorCode `json:"errorCode,omitempty"`
+
+	// TrackEvents is true if the evaluation of the flag is exported.
+	TrackEvents bool `json:"trackEvents"`
+
+	// Version is the version of the flag.
+	Version float64 `json:"version"`
+}
+
+// BoolEvaluationDetails is the result of BoolVariationDetails.
+type BoolEvaluationDetails struct {
+	Value bool `json:"value"`
+	EvaluationDetails
+}
+
+// IntEvaluationDetails is the result of IntVariationDetails.
+type IntEvaluationDetails struct {
+	Value int `json:"value"`
+	EvaluationDetails
+}
+
+// Float64EvaluationDetails is the result of Float64VariationDetails.
+type Float64EvaluationDetails struct {
+	Value float64 `json:"value"`
+	EvaluationDetails
+}
+
+// StringEvaluationDetails is the result of StringVariationDetails.
+type StringEvaluationDetails struct {
+	Value string `json:"value"`
+	EvaluationDetails
+}
+
+// JSONArrayEvaluationDetails is the result of JSONArrayVariationDetails.
+type JSONArrayEvaluationDetails struct {
+	Value []interface{} `json:"value"`
+	EvaluationDetails
+}
+
+// JSONEvaluationDetails is the result of JSONVariationDetails.
+type JSONEvaluationDetails struct {
+	Value map[string]interface{} `json:"value"`
+	EvaluationDetails
+}
+
+// BoolVariationDetails return the value of the flag in boolean with the details of the evaluation.
+// An error is return if the default value is served, the details contain the reason.
+func BoolVariationDetails(flagKey string, user ffuser.User, defaultValue bool) (BoolEvaluationDetails, error) {
+	return ff.BoolVariationDetails(flagKey, user, defaultValue)
+}
+
+// BoolVariationDetailsWithContext is the same as BoolVariationDetails, the context is passed to the hooks.
+func BoolVariationDetailsWithContext(
+	ctx context.Context, flagKey string, user ffuser.User, defaultValue bool,
+) (BoolEvaluationDetails, error) {
+	return ff.BoolVariationDetailsWithContext(ctx, flagKey, user, defaultValue)
+}
+
+// IntVariationDetails return the value of the flag in int with the details of the evaluation.
+// An error is return if the default value is served, the details contain the reason.
+func IntVariationDetails(flagKey string, user ffuser.User, defaultValue int) (IntEvaluationDetails, error) {
+	return ff.IntVariationDetails(flagKey, user, defaultValue)
+}
+
+// IntVariationDetailsWithContext is the same as IntVariationDetails, the context is passed to the hooks.
+func IntVariationDetailsWithContext(
+	ctx context.Context, flagKey string, user ffuser.User, defaultValue int,
+) (IntEvaluationDetails, error) {
+	return ff.IntVariationDetailsWithContext(ctx, flagKey, user, defaultValue)
+}
+
+// Float64VariationDetails return the value of the flag in float64 with the details of the evaluation.
+// An error is return if the default value is served, the details contain the reason.
+func Float64VariationDetails(
+	flagKey string, user ffuser.User, defaultValue float64,
+) (Float64EvaluationDetails, error) {
+	return ff.Float64VariationDetails(flagKey, user, defaultValue)
+}
+
+// Float64VariationDetailsWithContext is the same as Float64VariationDetails, the context is passed to the hooks.
+func Float64VariationDetailsWithContext(
+	ctx context.Context, flagKey string, user ffuser.User, defaultValue float64,
+) (Float64EvaluationDetails, error) {
+	return ff.Float64VariationDetailsWithContext(ctx, flagKey, user, defaultValue)
+}
+
+// StringVariationDetails return the value of the flag in string with the details of the evaluation.
+// An error is return if the default value is served, the details contain the reason.
+func StringVariationDetails(
+	flagKey string, user ffuser.User, defaultValue string,
+) (StringEvaluationDetails, error) {
+	return ff.StringVariationDetails(flagKey, user, defaultValue)
+}
+
+// StringVariationDetailsWithContext is the same as StringVariationDetails, the context is passed to the hooks.
+func StringVariationDetailsWithContext(
+	ctx context.Context, flagKey string, user ffuser.User, defaultValue string,
+) (StringEvaluationDetails, error) {
+	return ff.StringVariationDetailsWithContext(ctx, flagKey, user, defaultValue)
+}
+
+// JSONArrayVariationDetails return the value of the flag in []interface{} with the details of the evaluation.
+// An error is return if the default value is served, the details contain the reason.
+func JSONArrayVariationDetails(
+	flagKey string, user ffuser.User, defaultValue []interface{},
+) (JSONArrayEvaluationDetails, error) {
+	return ff.JSONArrayVariationDetails(flagKey, user, defaultValue)
+}
+
+// JSONArrayVariationDetailsWithContext is the same as JSONArrayVariationDetails, the context is passed to the hooks.
+func JSONArrayVariationDetailsWithContext(
+	ctx context.Context, flagKey string, user ffuser.User, defaultValue []interface{},
+) (JSONArrayEvaluationDetails, error) {
+	return ff.JSONArrayVariationDetailsWithContext(ctx, flagKey, user, defaultValue)
+}
+
+// JSONVariationDetails return the value of the flag in map[string]interface{} with the details of the evaluation.
+// An error is return if the default value is served, the details contain the reason.
+func JSONVariationDetails(
+	flagKey string, user ffuser.User, defaultValue map[string]interface{},
+) (JSONEvaluationDetails, error) {
+	return ff.JSONVariationDetails(flagKey, user, defaultValue)
+}
+
+// JSONVariationDetailsWithContext is the same as JSONVariationDetails, the context is passed to the hooks.
+func JSONVariationDetailsWithContext(
+	ctx context.Context, flagKey string, user ffuser.User, defaultValue map[string]interface{},
+) (JSONEvaluationDetails, error) {
+	return ff.JSONVariationDetailsWithContext(ctx, flagKey, user, defaultValue)
+}
+
+// BoolVariationDetails return the value of the flag in boolean with the details of the evaluation.
+// An error is return if the default value is served, the details contain the reason.
+// Note: Use this function only if you are using multiple go-feature-flag instances.
+func (g *GoFeatureFlag) BoolVariationDetails(
+	flagKey string, user ffuser.User, defaultValue bool,
+) (BoolEvaluationDetails, error) {
+	return g.BoolVariationDetailsWithContext(context.Background(), flagKey, user, defaultValue)
+}
+
+// BoolVariationDetailsWithContext is the same as BoolVariationDetails, the context is passed to the hooks.
+// Note: Use this function only if you are using multiple go-feature-flag instances.
+func (g *GoFeatureFlag) BoolVariationDetailsWithContext(
+	ctx context.Context, flagKey string, user ffuser.User, defaultValue bool,
+) (BoolEvaluationDetails, error) {
+	res, err := g.boolVariation(ctx, flagKey, user, defaultValue)
+	g.notifyVariation(flagKey, user, res.VariationResult, res.Value)
+	return BoolEvaluationDetails{Value: res.Value, EvaluationDetails: newEvaluationDetails(res.VariationResult)}, err
+}
+
+// IntVariationDetails return the value of the flag in int with the details of the evaluation.
+// An error is return if the default value is served, the details contain the reason.
+// Note: Use this function only if you are using multiple go-feature-flag instances.
+func (g *GoFeatureFlag) IntVariationDetails(
+	flagKey string, user ffuser.User, defaultValue int,
+) (IntEvaluationDetails, error) {
+	return g.IntVariationDetailsWithContext(context.Background(), flagKey, user, defaultValue)
+}
+
+// IntVariationDetailsWithContext is the same as IntVariationDetails, the context is passed to the hooks.
+// Note: Use this function only if you are using multiple go-feature-flag instances.
+func (g *GoFeatureFlag) IntVariationDetailsWithContext(
+	ctx context.Context, flagKey string, user ffuser.User, defaultValue int,
+) (IntEvaluationDetails, error) {
+	res, err := g.intVariation(ctx, flagKey, user, defaultValue)
+	g.notifyVariation(flagKey, user, res.VariationResult, res.Value)
+	return IntEvaluationDetails{Value: res.Value, EvaluationDetails: newEvaluationDetails(res.VariationResult)}, err
+}
+
+// Float64VariationDetails return the value of the flag in float64 with the details of the evaluation.
+// An error is return if the default value is served, the details contain the reason.
+// Note: Use this function only if you are using multiple go-feature-flag instances.
+func (g *GoFeatureFlag) Float64VariationDetails(
+	flagKey string, user ffuser.User, defaultValue float64,
+) (Float64EvaluationDetails, error) {
+	return g.Float64VariationDetailsWithContext(context.Background(), flagKey, user, defaultValue)
+}
+
+// Float64VariationDetailsWithContext is the same as Float64VariationDetails, the context is passed to the hooks.
+// Note: Use this function only if you are using multiple go-feature-flag instances.
+func (g *GoFeatureFlag) Float64VariationDetailsWithContext(
+	ctx context.Context, flagKey string, user ffuser.User, defaultValue float64,
+) (Float64EvaluationDetails, error) {
+	res, err := g.float64Variation(ctx, flagKey, user, defaultValue)
+	g.notifyVariation(flagKey, user, res.VariationResult, res.Value)
+	return Float64EvaluationDetails{Value: res.Value, EvaluationDetails: newEvaluationDetails(res.VariationResult)}, err
+}
+
+// StringVariationDetails return the value of the flag in string with the details of the evaluation.
+// An error is return if the default value is served, the details contain the reason.
+// Note: Use this function only if you are using multiple go-feature-flag instances.
+func (g *GoFeatureFlag) StringVariationDetails(
+	flagKey string, user ffuser.User, defaultValue string,
+) (StringEvaluationDetails, error) {
+	return g.StringVariationDetailsWithContext(context.Background(), flagKey, user, defaultValue)
+}
+
+// StringVariationDetailsWithContext is the same as StringVariationDetails, the context is passed to the hooks.
+// Note: Use this function only if you are using multiple go-feature-flag instances.
+func (g *GoFeatureFlag) StringVariationDetailsWithContext(
+	ctx context.Context, flagKey string, user ffuser.User, defaultValue string,
+) (StringEvaluationDetails, error) {
+	res, err := g.stringVariation(ctx, flagKey, user, defaultValue)
+	g.notifyVariation(flagKey, user, res.VariationResult, res.Value)
+	return StringEvaluationDetails{Value: res.Value, EvaluationDetails: newEvaluationDetails(res.VariationResult)}, err
+}
+
+// JSONArrayVariationDetails return the value of the flag in []interface{} with the details of the evaluation.
+// An error is return if the default value is served, the details contain the reason.
+// Note: Use this function only if you are using multiple go-feature-flag instances.
+func (g *GoFeatureFlag) JSONArrayVariationDetails(
+	flagKey string, user ffuser.User, defaultValue []interface{},
+) (JSONArrayEvaluationDetails, error) {
+	return g.JSONArrayVariationDetailsWithContext(context.Background(), flagKey, user, defaultValue)
+}
+
+// JSONArrayVariationDetailsWithContext is the same as JSONArrayVariationDetails, the context is passed to the hooks.
+// Note: Use this function only if you are using multiple go-feature-flag instances.
+func (g *GoFeatureFlag) JSONArrayVariationDetailsWithContext(
+	ctx context.Context, flagKey string, user ffuser.User, defaultValue []interface{},
+) (JSONArrayEvaluationDetails, error) {
+	res, err := g.jsonArrayVariation(ctx, flagKey, user, defaultValue)
+	g.notifyVariation(flagKey, user, res.VariationResult, res.Value)
+	return JSONArrayEvaluationDetails{
+		Value:             res.Value,
+		EvaluationDetails: newEvaluationDetails(res.VariationResult),
+	}, err
+}
+
+// JSONVariationDetails return the value of the flag in map[string]interface{} with the details of the evaluation.
+// An error is return if the default value is served, the details contain the reason.
+// Note: Use this function only if you are using multiple go-feature-flag instances.
+func (g *GoFeatureFlag) JSONVariationDetails(
+	flagKey string, user ffuser.User, defaultValue map[string]interface{},
+) (JSONEvaluationDetails, error) {
+	return g.JSONVariationDetailsWithContext(context.Background(), flagKey, user, defaultValue)
+}
+
+// JSONVariationDetailsWithContext is the same as JSONVariationDetails, the context is passed to the hooks.
+// Note: Use this function only if you are using multiple go-feature-flag instances.
+func (g *GoFeatureFlag) JSONVariationDetailsWithContext(
+	ctx context.Context, flagKey string, user ffuser.User, defaultValue map[string]interface{},
+) (JSONEvaluationDetails, error) {
+	res, err := g.jsonVariation(ctx, flagKey, user, defaultValue)
+	g.notifyVariation(flagKey, user, res.VariationResult, res.Value)
+	return JSONEvaluationDetails{Value: res.Value, EvaluationDetails: newEvaluationDetails(res.VariationResult)}, err
+}
+
+// newEvaluationDetails converts the internal result of a variation to the public EvaluationDetails.
+func newEvaluationDetails(res model.VariationResult) EvaluationDetails {
+	return EvaluationDetails{
+		VariationName:        res.VariationType,
+		Reason:               res.Reason,
+		RuleIndex:            res.RuleIndex,
+		RuleName:             res.RuleName,
+		PrerequisiteKey:      res.PrerequisiteKey,
+		BucketingKeyFallback: res.BucketingKeyFallback,
+		ErrorCode:            res.ErrorCode,
+		TrackEvents:          res.TrackEvents,
+		Version:              res.Version,
+	}
+}
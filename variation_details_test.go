@@ -0,0 +1,140 @@
+package ffclient_test
+
+import (
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	ffclient "github.com/thomaspoignant/go-feature-flag"
+	"github.com/thomaspoignant/go-feature-flag/ffuser"
+	"github.com/thomaspoignant/go-feature-flag/testutils/testconvert"
+)
+
+func TestVariationDetails(t *testing.T) {
+	gffClient, err := ffclient.New(ffclient.Config{
+		PollingInterval: 5 * time.Second,
+		Retriever:       &ffclient.FileRetriever{Path: "testdata/ffclient/variation_details/flag-config.yaml"},
+		Logger:          log.New(os.Stdout, "", 0),
+	})
+	assert.NoError(t, err)
+	defer gffClient.Close()
+
+	user := ffuser.NewUser("random-key")
+	betaUser := ffuser.NewUserBuilder("beta-key").AddCustom("beta", true).Build()
+
+	color, err := gffClient.StringVariationDetails("checkout-color", ffuser.NewUser("targeted-key"), "black")
+	assert.NoError(t, err)
+	assert.Equal(t, "green", color.Value)
+	assert.Equal(t, "green", color.VariationName)
+	assert.Equal(t, ffclient.ReasonTargetMatch, color.Reason)
+	assert.Equal(t, "TARGETING_MATCH", string(color.Reason))
+
+	color, err = gffClient.StringVariationDetails("checkout-color", betaUser, "black")
+	assert.NoError(t, err)
+	assert.Equal(t, "red", color.Value)
+	assert.Equal(t, ffclient.ReasonRuleMatch, color.Reason)
+	assert.Equal(t, testconvert.Int(0), color.RuleIndex)
+	assert.Equal(t, testconvert.String("beta users"), color.RuleName)
+
+	color, err = gffClient.StringVariationDetails("checkout-color", user, "black")
+	assert.NoError(t, err)
+	assert.Equal(t, "blue", color.Value)
+	assert.Equal(t, ffclient.ReasonDefault, color.Reason)
+	assert.Nil(t, color.RuleIndex)
+
+	enabled, err := gffClient.BoolVariationDetails("disabled-flag", user, false)
+	assert.False(t, enabled.Value)
+	assert.Equal(t, "SdkDefault", enabled.VariationName)
+	assert.ErrorIs(t, err, ffclient.ErrFlagDisabled)
+	assert.Equal(t, ffclient.ReasonDisabled, enabled.Reason)
+	assert.Empty(t, enabled.ErrorCode)
+
+	enabled, err = gffClient.BoolVariationDetails("old-experiment", user, false)
+	assert.NoError(t, err)
+	assert.Equal(t, ffclient.ReasonExperimentNotRunning, enabled.Reason)
+
+	enabled, err = gffClient.BoolVariationDetails("unknown-flag", user, true)
+	assert.Error(t, err)
+	assert.True(t, enabled.Value)
+	assert.Equal(t, ffclient.ReasonFlagNotFound, enabled.Reason)
+	assert.Equal(t, ffclient.ErrorCodeFlagNotFound, enabled.ErrorCode)
+
+	number, err := gffClient.IntVariationDetails("new-checkout", user, 42)
+	assert.Error(t, err)
+	assert.Equal(t, 42, number.Value)
+	assert.Equal(t, ffclient.ReasonTypeMismatch, number.Reason)
+	assert.Equal(t, ffclient.ErrorCodeTypeMismatch, number.ErrorCode)
+}
+
+func TestVariationDetailsOffline(t *testing.T) {
+	gffClient, err := ffclient.New(ffclient.Config{
+		Retriever: &ffclient.FileRetriever{Path: "testdata/ffclient/variation_details/flag-config.yaml"},
+		Offline:   true,
+	})
+	assert.NoError(t, err)
+	defer gffClient.Close()
+
+	res, err := gffClient.StringVariationDetails("checkout-color", ffuser.NewUser("random-key"), "black")
+	assert.ErrorIs(t, err, ffclient.ErrOffline)
+	assert.Equal(t, "black", res.Value)
+	assert.Equal(t, ffclient.ReasonOffline, res.Reason)
+}
+
+func TestVariationDetailsSplitDefaultRule(t *testing.T) {
+	gffClient, err := ffclient.New(ffclient.Config{
+		PollingInterval: 5 * time.Second,
+		Retriever:       &ffclient.FileRetriever{Path: "testdata/ffclient/no_default_variation/flag-config.yaml"},
+	})
+	assert.NoError(t, err)
+	defer gffClient.Close()
+
+	tests := []struct {
+		name       string
+		flagKey    string
+		wantReason ffclient.EvaluationReason
+		wantErr    error
+	}{
+		{
+			name:       "disabled flag with a split default rule",
+			flagKey:    "disabled-split",
+			wantReason: ffclient.ReasonDisabled,
+			wantErr:    ffclient.ErrFlagDisabled,
+		},
+		{
+			name:       "experimentation window in the future with a split default rule",
+			flagKey:    "future-experiment-split",
+			wantReason: ffclient.ReasonExperimentNotRunning,
+			wantErr:    ffclient.ErrFlagDisabled,
+		},
+		{
+			name:       "failed prerequisite with a split default rule",
+			flagKey:    "prerequisite-failed-split",
+			wantReason: ffclient.ReasonPrerequisiteFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := gffClient.BoolVariationDetails(tt.flagKey, ffuser.NewUser("random-key"), true)
+			assert.Error(t, err)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+			assert.True(t, res.Value, "should serve the SDK default value")
+			assert.Equal(t, "SdkDefault", res.VariationName)
+			assert.Equal(t, tt.wantReason, res.Reason)
+			assert.Empty(t, res.ErrorCode)
+		})
+	}
+
+	allFlags := gffClient.AllFlagsStateWithOptions(ffuser.NewUser("random-key"),
+		ffclient.AllFlagsStateOptions{WithReasons: true})
+	assert.True(t, allFlags.IsValid())
+	flags := allFlags.GetFlags()
+	assert.Len(t, flags, 3)
+	assert.Nil(t, flags["disabled-split"].Value)
+	assert.Equal(t, "SdkDefault", flags["disabled-split"].VariationType)
+	assert.Equal(t, ffclient.ReasonDisabled, flags["disabled-split"].Reason)
+	assert.Equal(t, ffclient.ReasonExperimentNotRunning, flags["future-experiment-split"].Reason)
+}
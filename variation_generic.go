@@ -0,0 +1,180 @@
+package ffclient
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/thomaspoignant/go-feature-flag/ffuser"
+	"github.com/thomaspoignant/go-feature-flag/internal/model"
+)
+
+// timeLayouts are the formats accepted to convert the value of a flag to a time.Time.
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02"}
+
+// Variation return the value of the flag converted to the type T.
+// If the value of the flag is a JSON object or a JSON array, it is decoded in T,
+// it allows to read a flag directly in a struct (the json tags of the struct are used).
+// If g is nil, the instance created by ffclient.Init is used.
+// If the value cannot be converted, the default value is returned with the error ErrTypeMismatch.
+func Variation[T any](g *GoFeatureFlag, flagKey string, user ffuser.User, defaultValue T) (T, error) {
+	return VariationWithContext(context.Background(), g, flagKey, user, defaultValue)
+}
+
+// VariationWithContext is the same as Variation, the context is passed to the hooks.
+func VariationWithContext[T any](
+	ctx context.Context, g *GoFeatureFlag, flagKey string, user ffuser.User, defaultValue T,
+) (T, error) {
+	if g == nil {
+		g = ff
+	}
+	value, varResult, err := variation(ctx, g, flagKey, user, defaultValue)
+	g.notifyVariation(flagKey, user, varResult, value)
+	return value, err
+}
+
+// Int64Variation return the value of the flag in int64.
+// An error is return if you don't have init the library before calling the function.
+// If the key does not exist we return the default value.
+func Int64Variation(flagKey string, user ffuser.User, defaultValue int64) (int64, error) {
+	return ff.Int64Variation(flagKey, user, defaultValue)
+}
+
+// DurationVariation return the value of the flag in time.Duration, the value of the flag
+// should be a duration string such as "300ms" or "1h30m".
+// An error is return if you don't have init the library before calling the function.
+// If the key does not exist we return the default value.
+func DurationVariation(flagKey string, user ffuser.User, defaultValue time.Duration) (time.Duration, error) {
+	return ff.DurationVariation(flagKey, user, defaultValue)
+}
+
+// TimeVariation return the value of the flag in time.Time, the value of the flag should be
+// a date in the format RFC3339 or YYYY-MM-DD.
+// An error is return if you don't have init the library before calling the function.
+// If the key does not exist we return the default value.
+func TimeVariation(flagKey string, user ffuser.User, defaultValue time.Time) (time.Time, error) {
+	return ff.TimeVariation(flagKey, user, defaultValue)
+}
+
+// Int64Variation return the value of the flag in int64.
+// An error is return if you don't have init the library before calling the function.
+// If the key does not exist we return the default value.
+// Note: Use this function only if you are using multiple go-feature-flag instances.
+func (g *GoFeatureFlag) Int64Variation(flagKey string, user ffuser.User, defaultValue int64) (int64, error) {
+	return Variation(g, flagKey, user, defaultValue)
+}
+
+// DurationVariation return the value of the flag in time.Duration, the value of the flag
+// should be a duration string such as "300ms" or "1h30m".
+// An error is return if you don't have init the library before calling the function.
+// If the key does not exist we return the default value.
+// Note: Use this function only if you are using multiple go-feature-flag instances.
+func (g *GoFeatureFlag) DurationVariation(
+	flagKey string, user ffuser.User, defaultValue time.Duration,
+) (time.Duration, error) {
+	return Variation(g, flagKey, user, defaultValue)
+}
+
+// TimeVariation return the value of the flag in time.Time, the value of the flag should be
+// a date in the format RFC3339 or YYYY-MM-DD.
+// An error is return if you don't have init the library before calling the function.
+// If the key does not exist we return the default value.
+// Note: Use this function only if you are using multiple go-feature-flag instances.
+func (g *GoFeatureFlag) TimeVariation(flagKey string, user ffuser.User, defaultValue time.Time) (time.Time, error) {
+	return Variation(g, flagKey, user, defaultValue)
+}
+
+// variation is the internal func that handle the logic of a variation,
+// the value of the flag is converted to T and the default value is used if it is not possible.
+func variation[T any](ctx context.Context, g *GoFeatureFlag, flagKey string, user ffuser.User, sdkDefaultValue T,
+) (T, model.VariationResult, error) {
+	return evaluateWithHooks(ctx, g, flagKey, user, sdkDefaultValue, func() (T, model.VariationResult, error) {
+		return evaluateVariation(g, flagKey, user, sdkDefaultValue)
+	})
+}
+
+// evaluateVariation evaluates the flag and converts its value to T.
+func evaluateVariation[T any](g *GoFeatureFlag, flagKey string, user ffuser.User, sdkDefaultValue T,
+) (T, model.VariationResult, error) {
+	if g.config.Offline {
+		return sdkDefaultValue, offlineVariationResult, newOfflineError(flagKey)
+	}
+
+	f, err := g.getFlagFromCache(flagKey)
+	if err != nil {
+		return sdkDefaultValue, computeErrorVariationResult(f, err), err
+	}
+
+	flagValue, resolutionDetails, err := g.evaluate(flagKey, f, user)
+	if err != nil {
+		return sdkDefaultValue, computeErrorVariationResult(f, err), err
+	}
+	if resolutionDetails.Variant == "" {
+		err := newNoVariationError(flagKey, resolutionDetails.Reason)
+		return sdkDefaultValue, computeErrorVariationResult(f, err), err
+	}
+	res, ok := convertValue[T](flagValue)
+	if !ok {
+		err := newTypeMismatchError(flagKey)
+		return sdkDefaultValue, computeErrorVariationResult(f, err), err
+	}
+	return res, computeVariationResult(f, resolutionDetails), nil
+}
+
+// convertValue converts the value of a flag to the type T, it returns false if the conversion is not possible.
+func convertValue[T any](value interface{}) (T, bool) {
+	if res, ok := value.(T); ok {
+		return res, true
+	}
+
+	var res T
+	switch ptr := interface{}(&res).(type) {
+	case *int:
+		// if this is a float64 we convert it to int
+		resFloat, ok := value.(float64)
+		*ptr = int(resFloat)
+		return res, ok
+	case *int64:
+		switch v := value.(type) {
+		case int:
+			*ptr = int64(v)
+			return res, true
+		case float64:
+			*ptr = int64(v)
+			return res, true
+		}
+		return res, false
+	case *time.Duration:
+		raw, ok := value.(string)
+		if !ok {
+			return res, false
+		}
+		duration, err := time.ParseDuration(raw)
+		*ptr = duration
+		return res, err == nil
+	case *time.Time:
+		raw, ok := value.(string)
+		if !ok {
+			return res, false
+		}
+		for _, layout := range timeLayouts {
+			if date, err := time.Parse(layout, raw); err == nil {
+				*ptr = date
+				return res, true
+			}
+		}
+		return res, false
+	}
+
+	// JSON objects and arrays are decoded in T, it allows to read the flag in a struct.
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		content, err := json.Marshal(value)
+		if err != nil {
+			return res, false
+		}
+		err = json.Unmarshal(content, &res)
+		return res, err == nil
+	}
+	return res, false
+}
@@ -0,0 +1,81 @@
+package ffclient_test
+
+import (
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	ffclient "github.com/thomaspoignant/go-feature-flag"
+	"github.com/thomaspoignant/go-feature-flag/ffuser"
+)
+
+type rateLimitPolicy struct {
+	RequestsPerSecond int      `json:"requestsPerSecond"`
+	Burst             int      `json:"burst"`
+	Paths             []string `json:"paths"`
+}
+
+func TestVariationGeneric(t *testing.T) {
+	gffClient, err := ffclient.New(ffclient.Config{
+		PollingInterval: 5 * time.Second,
+		Retriever:       &ffclient.FileRetriever{Path: "testdata/ffclient/variation_generic/flag-config.yaml"},
+		Logger:          log.New(os.Stdout, "", 0),
+	})
+	assert.NoError(t, err)
+	defer gffClient.Close()
+
+	user := ffuser.NewUser("random-key")
+	premiumUser := ffuser.NewUserBuilder("premium-key").AddCustom("plan", "premium").Build()
+
+	policy, err := ffclient.Variation(gffClient, "rate-limit-policy", user, rateLimitPolicy{})
+	assert.NoError(t, err)
+	assert.Equal(t, rateLimitPolicy{RequestsPerSecond: 100, Burst: 20, Paths: []string{"/api"}}, policy)
+
+	policy, err = ffclient.Variation(gffClient, "rate-limit-policy", premiumUser, rateLimitPolicy{})
+	assert.NoError(t, err)
+	assert.Equal(t, rateLimitPolicy{RequestsPerSecond: 1000, Burst: 200, Paths: []string{"/api", "/admin"}}, policy)
+
+	name, err := ffclient.Variation(gffClient, "feature-name", user, "default")
+	assert.NoError(t, err)
+	assert.Equal(t, "new-checkout", name)
+
+	defaultPolicy := rateLimitPolicy{RequestsPerSecond: 1}
+	policy, err = ffclient.Variation(gffClient, "feature-name", user, defaultPolicy)
+	assert.ErrorIs(t, err, ffclient.ErrTypeMismatch)
+	assert.Equal(t, defaultPolicy, policy)
+
+	policy, err = ffclient.Variation(gffClient, "unknown-flag", user, defaultPolicy)
+	assert.ErrorIs(t, err, ffclient.ErrFlagNotFound)
+	assert.Equal(t, defaultPolicy, policy)
+
+	size, err := gffClient.Int64Variation("max-upload-size", user, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1073741824), size)
+
+	timeout, err := gffClient.DurationVariation("request-timeout", user, time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, 90*time.Second, timeout)
+
+	timeout, err = gffClient.DurationVariation("feature-name", user, time.Second)
+	assert.ErrorIs(t, err, ffclient.ErrTypeMismatch)
+	assert.Equal(t, time.Second, timeout)
+
+	sunset, err := gffClient.TimeVariation("sunset-date", user, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2024, time.January, 31, 0, 0, 0, 0, time.UTC), sunset)
+}
+
+func TestVariationGenericDefaultInstance(t *testing.T) {
+	err := ffclient.Init(ffclient.Config{
+		PollingInterval: 5 * time.Second,
+		Retriever:       &ffclient.FileRetriever{Path: "testdata/ffclient/variation_generic/flag-config.yaml"},
+	})
+	assert.NoError(t, err)
+	defer ffclient.Close()
+
+	timeout, err := ffclient.Variation[time.Duration](nil, "request-timeout", ffuser.NewUser("random-key"), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 90*time.Second, timeout)
+}
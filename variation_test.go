@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
@@ -39,6 +40,10 @@ func (c *cacheMock) GetLatestUpdateDate() time.Time {
 	return time.Now()
 }
 
+func (c *cacheMock) GetLatestCheckDate() time.Time {
+	return time.Now()
+}
+
 func (c *cacheMock) UpdateCache(loadedFlags []byte, fileFormat string) error {
 	return nil
 }
@@ -47,6 +52,10 @@ func (c *cacheMock) GetFlag(key string) (flag.Flag, error) {
 	return c.flag, c.err
 }
 func (c *cacheMock) AllFlags() (map[string]flag.Flag, error) { return nil, nil }
+func (c *cacheMock) GetSegments() (flag.Segments, error)     { return nil, nil }
+func (c *cacheMock) Snapshot() (cache.Snapshot, error) {
+	return cache.NewSnapshot(map[string]flag.Flag{}, nil), nil
+}
 
 func TestBoolVariation(t *testing.T) {
 	type args struct {
@@ -205,7 +214,7 @@ func TestBoolVariation(t *testing.T) {
 				}, nil),
 			},
 			want:        false,
-			wantErr:     false,
+			wantErr:     true,
 			expectedLog: "",
 		},
 	}
@@ -239,6 +248,10 @@ func TestBoolVariation(t *testing.T) {
 
 			if tt.wantErr {
 				assert.Error(t, err, "BoolVariation() error = %v, wantErr %v", err, tt.wantErr)
+				if tt.args.offline {
+					assert.ErrorIs(t, err, ErrOffline)
+					assert.Equal(t, tt.want, got, "BoolVariation() got = %v, want %v", got, tt.want)
+				}
 				return
 			}
 			assert.Equal(t, tt.want, got, "BoolVariation() got = %v, want %v", got, tt.want)
@@ -407,7 +420,7 @@ func TestFloat64Variation(t *testing.T) {
 				}, nil),
 			},
 			want:        118.12,
-			wantErr:     false,
+			wantErr:     true,
 			expectedLog: "",
 		},
 	}
@@ -440,6 +453,10 @@ func TestFloat64Variation(t *testing.T) {
 			}
 			if tt.wantErr {
 				assert.Error(t, err, "Float64Variation() error = %v, wantErr %v", err, tt.wantErr)
+				if tt.args.offline {
+					assert.ErrorIs(t, err, ErrOffline)
+					assert.Equal(t, tt.want, got, "Float64Variation() got = %v, want %v", got, tt.want)
+				}
 				return
 			}
 			assert.Equal(t, tt.want, got, "Float64Variation() got = %v, want %v", got, tt.want)
@@ -626,7 +643,7 @@ func TestJSONArrayVariation(t *testing.T) {
 				}, nil),
 			},
 			want:        []interface{}{"toto"},
-			wantErr:     false,
+			wantErr:     true,
 			expectedLog: "",
 		},
 	}
@@ -652,6 +669,10 @@ func TestJSONArrayVariation(t *testing.T) {
 
 			if tt.wantErr {
 				assert.Error(t, err, "JSONArrayVariation() error = %v, wantErr %v", err, tt.wantErr)
+				if tt.args.offline {
+					assert.ErrorIs(t, err, ErrOffline)
+					assert.Equal(t, tt.want, got, "JSONArrayVariation() got = %v, want %v", got, tt.want)
+				}
 				return
 			}
 			assert.Equal(t, tt.want, got, "JSONArrayVariation() got = %v, want %v", got, tt.want)
@@ -804,7 +825,7 @@ func TestJSONVariation(t *testing.T) {
 				}, nil),
 			},
 			want:        map[string]interface{}{"default-notkey": true},
-			wantErr:     false,
+			wantErr:     true,
 			expectedLog: "",
 		},
 	}
@@ -838,6 +859,10 @@ func TestJSONVariation(t *testing.T) {
 
 			if tt.wantErr {
 				assert.Error(t, err, "JSONVariation() error = %v, wantErr %v", err, tt.wantErr)
+				if tt.args.offline {
+					assert.ErrorIs(t, err, ErrOffline)
+					assert.Equal(t, tt.want, got, "JSONVariation() got = %v, want %v", got, tt.want)
+				}
 				return
 			}
 			assert.Equal(t, tt.want, got, "JSONVariation() got = %v, want %v", got, tt.want)
@@ -989,7 +1014,7 @@ func TestStringVariation(t *testing.T) {
 				}, nil),
 			},
 			want:        "default-notkey",
-			wantErr:     false,
+			wantErr:     true,
 			expectedLog: "",
 		},
 	}
@@ -1022,6 +1047,10 @@ func TestStringVariation(t *testing.T) {
 
 			if tt.wantErr {
 				assert.Error(t, err, "StringVariation() error = %v, wantErr %v", err, tt.wantErr)
+				if tt.args.offline {
+					assert.ErrorIs(t, err, ErrOffline)
+					assert.Equal(t, tt.want, got, "StringVariation() got = %v, want %v", got, tt.want)
+				}
 				return
 			}
 			assert.Equal(t, tt.want, got, "StringVariation() got = %v, want %v", got, tt.want)
@@ -1190,7 +1219,7 @@ func TestIntVariation(t *testing.T) {
 				}, nil),
 			},
 			want:        125,
-			wantErr:     false,
+			wantErr:     true,
 			expectedLog: "",
 		},
 	}
@@ -1223,6 +1252,10 @@ func TestIntVariation(t *testing.T) {
 
 			if tt.wantErr {
 				assert.Error(t, err, "IntVariation() error = %v, wantErr %v", err, tt.wantErr)
+				if tt.args.offline {
+					assert.ErrorIs(t, err, ErrOffline)
+					assert.Equal(t, tt.want, got, "IntVariation() got = %v, want %v", got, tt.want)
+				}
 				return
 			}
 			assert.Equal(t, tt.want, got, "IntVariation() got = %v, want %v", got, tt.want)
@@ -1419,6 +1452,7 @@ func TestRawVariation(t *testing.T) {
 				Value: true,
 				VariationResult: model.VariationResult{
 					VariationType: flag.VariationSDKDefault,
+					Reason:        flag.ReasonDisabled,
 					Failed:        true,
 					TrackEvents:   true,
 				},
@@ -1440,6 +1474,8 @@ func TestRawVariation(t *testing.T) {
 				Value: "defaultValue",
 				VariationResult: model.VariationResult{
 					VariationType: flag.VariationSDKDefault,
+					Reason:        flag.ReasonError,
+					ErrorCode:     flag.ErrorCodeGeneral,
 					Failed:        true,
 					TrackEvents:   true,
 				},
@@ -1453,12 +1489,14 @@ func TestRawVariation(t *testing.T) {
 				flagKey:      "key-not-exist",
 				user:         ffuser.NewUser("random-key"),
 				defaultValue: 123456,
-				cacheMock:    NewCacheMock(&flagv1.FlagData{}, errors.New("flag [key-not-exist] does not exists")),
+				cacheMock:    NewCacheMock(&flagv1.FlagData{}, fmt.Errorf("flag [key-not-exist] does not exists: %w", cache.ErrFlagNotFound)),
 			},
 			want: model.RawVarResult{
 				Value: 123456,
 				VariationResult: model.VariationResult{
 					VariationType: flag.VariationSDKDefault,
+					Reason:        flag.ReasonFlagNotFound,
+					ErrorCode:     flag.ErrorCodeFlagNotFound,
 					Failed:        true,
 					TrackEvents:   true,
 				},
@@ -1484,6 +1522,7 @@ func TestRawVariation(t *testing.T) {
 				Value: map[string]interface{}{"test": "test"},
 				VariationResult: model.VariationResult{
 					VariationType: "Default",
+					Reason:        flag.ReasonDefault,
 					Failed:        false,
 					TrackEvents:   true,
 				},
@@ -1509,6 +1548,7 @@ func TestRawVariation(t *testing.T) {
 				Value: map[string]interface{}{"test2": "test"},
 				VariationResult: model.VariationResult{
 					VariationType: "True",
+					Reason:        flag.ReasonRuleMatch,
 					Failed:        false,
 					TrackEvents:   true,
 				},
@@ -1534,6 +1574,7 @@ func TestRawVariation(t *testing.T) {
 				Value: map[string]interface{}{"test3": "test"},
 				VariationResult: model.VariationResult{
 					VariationType: "False",
+					Reason:        flag.ReasonRuleMatch,
 					Failed:        false,
 					TrackEvents:   true,
 				},
@@ -1560,6 +1601,7 @@ func TestRawVariation(t *testing.T) {
 				Value: true,
 				VariationResult: model.VariationResult{
 					VariationType: "True",
+					Reason:        flag.ReasonRuleMatch,
 					Failed:        false,
 					TrackEvents:   false,
 				},
@@ -1582,11 +1624,12 @@ func TestRawVariation(t *testing.T) {
 				Value: false,
 				VariationResult: model.VariationResult{
 					VariationType: flag.VariationSDKDefault,
+					Reason:        flag.ReasonOffline,
 					Failed:        true,
 					TrackEvents:   false,
 				},
 			},
-			wantErr:     false,
+			wantErr:     true,
 			expectedLog: "",
 		},
 	}
@@ -1620,6 +1663,9 @@ func TestRawVariation(t *testing.T) {
 
 			if tt.wantErr {
 				assert.Error(t, err, "RawVariation() error = %v, wantErr %v", err, tt.wantErr)
+				if tt.args.offline {
+					assert.ErrorIs(t, err, ErrOffline)
+				}
 			}
 			assert.Equal(t, tt.want, got, "RawVariation() got = %v, want %v", got, tt.want)
 